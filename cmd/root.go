@@ -9,6 +9,7 @@ import (
 	"github.com/user/gitgud/internal/commands"
 	"github.com/user/gitgud/internal/config"
 	"github.com/user/gitgud/internal/git"
+	"github.com/user/gitgud/internal/hook"
 )
 
 var rootCmd = &cobra.Command{
@@ -22,15 +23,24 @@ It supports all standard Git commands while adding intelligent autocommit functi
 	},
 }
 
+var (
+	autocommitOpts        autocommit.Options
+	autocommitPerFileOpts autocommit.Options
+)
+
 var autocommitCmd = &cobra.Command{
 	Use:     "autocommit",
 	Aliases: []string{"ac"},
 	Short:   "Generate AI-powered commit messages for all changes",
 	Long: `Autocommit analyzes your changes and generates intelligent commit messages
-using OpenAI. It follows Conventional Commits format and considers your branch
+using the configured LLM provider. It follows Conventional Commits format and considers your branch
 name and previous commit context.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		autocommit.HandleAutoCommit()
+		autocommitOpts.Generation.Temperature = temperatureOverride(cmd)
+		autocommitOpts.Context = contextOverride(cmd)
+		autocommitOpts.Staged = stagedOverride(cmd)
+		autocommitOpts.SignOff = signOffOverride(cmd)
+		autocommit.HandleAutoCommit(autocommitOpts)
 	},
 }
 
@@ -42,7 +52,9 @@ var acpfCmd = &cobra.Command{
 individually or in batches. Each selection gets its own AI-generated commit message
 with retry functionality.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		autocommit.HandleAutoCommitPerFile()
+		autocommitPerFileOpts.Generation.Temperature = temperatureOverride(cmd)
+		autocommitPerFileOpts.SignOff = signOffOverride(cmd)
+		autocommit.HandleAutoCommitPerFile(autocommitPerFileOpts)
 	},
 }
 
@@ -64,6 +76,70 @@ var configResetCmd = &cobra.Command{
 	},
 }
 
+var hookForce bool
+
+var hookCmd = &cobra.Command{
+	Use:   "hook",
+	Short: "Install or remove git hooks that run GitGud",
+	Long: `Manage git hooks that run GitGud from plain 'git commit', including commits made
+from an IDE's git integration.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+var hookInstallCmd = &cobra.Command{
+	Use:   "install <hook>",
+	Short: "Install a git hook",
+	Long: `Install a git hook into the current repository. Supported hooks:
+
+  prepare-commit-msg  Pre-fill the message of plain 'git commit' runs with an AI-generated one
+  commit-msg          Reject commit messages that break the autocommit rules`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		hook.HandleInstall(args[0], hookForce)
+	},
+}
+
+var hookUninstallCmd = &cobra.Command{
+	Use:   "uninstall [hook...]",
+	Short: "Remove git hooks installed by GitGud",
+	Long:  `Remove the given git hooks, or every hook installed by GitGud when none are given.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		hook.HandleUninstall(args)
+	},
+}
+
+// hookRunCmd is what the installed hook scripts call
+var hookRunCmd = &cobra.Command{
+	Use:                "run <hook> [args...]",
+	Short:              "Run a hook (called by the installed hook scripts)",
+	Hidden:             true,
+	DisableFlagParsing: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 2 || args[0] != hook.PrepareCommitMsg {
+			os.Exit(0)
+		}
+		source := ""
+		if len(args) > 2 {
+			source = args[2]
+		}
+		autocommit.HandlePrepareCommitMsg(autocommit.Options{}, args[1], source)
+	},
+}
+
+var lintMessageCmd = &cobra.Command{
+	Use:   "lint-message <file>",
+	Short: "Check a commit message against the autocommit rules",
+	Long: `Check a commit message file against the project's autocommit rules: Conventional Commit
+grammar, allowed types and scopes, and subject length. Exits with status 1 when the message
+breaks the rules. This is what the commit-msg hook runs.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		autocommit.HandleLintMessage(args[0])
+	},
+}
+
 var lastCmd = &cobra.Command{
 	Use:   "last",
 	Short: "Show detailed information about the last commit",
@@ -96,13 +172,40 @@ func Execute() {
 }
 
 func init() {
+	// Add autocommit flags
+	addGenerationFlags(autocommitCmd, &autocommitOpts.Generation)
+	addGenerationFlags(acpfCmd, &autocommitPerFileOpts.Generation)
+	addTrailerFlags(autocommitCmd, &autocommitOpts)
+	addTrailerFlags(acpfCmd, &autocommitPerFileOpts)
+	autocommitCmd.Flags().BoolVar(&autocommitOpts.NoCache, "no-cache", false, "Always generate a new message instead of reusing a cached one")
+	acpfCmd.Flags().BoolVar(&autocommitPerFileOpts.NoCache, "no-cache", false, "Always generate a new message instead of reusing a cached one")
+	autocommitCmd.Flags().BoolVar(&autocommitOpts.NoStream, "no-stream", false, "Wait for the full message instead of streaming it as it is generated")
+	acpfCmd.Flags().BoolVar(&autocommitPerFileOpts.NoStream, "no-stream", false, "Wait for the full message instead of streaming it as it is generated")
+	autocommitCmd.Flags().BoolVar(&autocommitOpts.Offline, "offline", false, "Generate the message locally from the diff without contacting a provider")
+	autocommitCmd.Flags().IntVar(&autocommitOpts.Candidates, "candidates", 1, "Generate this many messages and choose one from a list")
+	autocommitCmd.Flags().Bool("staged", false, "Use and commit only the staged changes (overrides config)")
+	autocommitCmd.Flags().BoolVar(&autocommitOpts.Amend, "amend", false, "Regenerate the message of the last commit and amend it with any staged changes")
+	autocommitCmd.Flags().BoolVar(&autocommitOpts.Force, "force", false, "Amend the last commit even if it was already pushed")
+	autocommitCmd.Flags().String("context", "", "Additional context for the commit message instead of asking for it")
+	autocommitCmd.Flags().BoolVarP(&autocommitOpts.Yes, "yes", "y", false, "Commit the generated message without asking for confirmation")
+	autocommitCmd.Flags().BoolVar(&autocommitOpts.DryRun, "dry-run", false, "Print the generated message without committing")
+	autocommitCmd.Flags().BoolVar(&autocommitOpts.MessageOnly, "message-only", false, "Write only the generated message to stdout without committing")
+
 	// Add config subcommands
 	configCmd.AddCommand(configResetCmd)
 
+	// Add hook subcommands
+	hookInstallCmd.Flags().BoolVar(&hookForce, "force", false, "Replace an existing hook that was not installed by gg")
+	hookCmd.AddCommand(hookInstallCmd)
+	hookCmd.AddCommand(hookUninstallCmd)
+	hookCmd.AddCommand(hookRunCmd)
+
 	// Add all commands to root
 	rootCmd.AddCommand(autocommitCmd)
 	rootCmd.AddCommand(acpfCmd)
 	rootCmd.AddCommand(configCmd)
+	rootCmd.AddCommand(hookCmd)
+	rootCmd.AddCommand(lintMessageCmd)
 	rootCmd.AddCommand(lastCmd)
 	rootCmd.AddCommand(gitCmd)
 
@@ -125,6 +228,56 @@ func init() {
 	addGitCommand("stash", "Stash the changes in a dirty working directory away")
 }
 
+// addGenerationFlags registers the model override flags shared by the autocommit commands
+func addGenerationFlags(cmd *cobra.Command, settings *config.GenerationSettings) {
+	cmd.Flags().StringVar(&settings.Model, "model", "", "Model used to generate the commit message (overrides config)")
+	cmd.Flags().IntVar(&settings.MaxTokens, "max-tokens", 0, "Maximum tokens for the generated message (overrides config)")
+	cmd.Flags().Float32("temperature", 0, "Sampling temperature for the model (overrides config)")
+}
+
+// addTrailerFlags registers the trailer flags shared by the autocommit commands
+func addTrailerFlags(cmd *cobra.Command, opts *autocommit.Options) {
+	cmd.Flags().StringArrayVar(&opts.CoAuthors, "co-author", nil, "Add a Co-authored-by trailer for \"Name <email>\" (repeatable)")
+	cmd.Flags().BoolVar(&opts.PickCoAuthors, "pair", false, "Pick co-authors from the recent authors of the repository")
+	cmd.Flags().BoolP("signoff", "s", false, "Add a Signed-off-by trailer (overrides config)")
+}
+
+// temperatureOverride returns the --temperature value, or nil when the flag was not given
+func temperatureOverride(cmd *cobra.Command) *float32 {
+	if !cmd.Flags().Changed("temperature") {
+		return nil
+	}
+	temperature, _ := cmd.Flags().GetFloat32("temperature")
+	return &temperature
+}
+
+// contextOverride returns the --context value, or nil when the flag was not given
+func contextOverride(cmd *cobra.Command) *string {
+	if !cmd.Flags().Changed("context") {
+		return nil
+	}
+	context, _ := cmd.Flags().GetString("context")
+	return &context
+}
+
+// stagedOverride returns the --staged value, or nil when the flag was not given
+func stagedOverride(cmd *cobra.Command) *bool {
+	if !cmd.Flags().Changed("staged") {
+		return nil
+	}
+	staged, _ := cmd.Flags().GetBool("staged")
+	return &staged
+}
+
+// signOffOverride returns the --signoff value, or nil when the flag was not given
+func signOffOverride(cmd *cobra.Command) *bool {
+	if !cmd.Flags().Changed("signoff") {
+		return nil
+	}
+	signOff, _ := cmd.Flags().GetBool("signoff")
+	return &signOff
+}
+
 func addGitCommand(name, description string) {
 	cmd := &cobra.Command{
 		Use:                name,
@@ -3,13 +3,12 @@ package autocommit
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"strings"
 
-	openai "github.com/sashabaranov/go-openai"
 	"github.com/user/gitgud/internal/config"
 	"github.com/user/gitgud/internal/git"
 	"github.com/user/gitgud/internal/ui"
@@ -19,29 +18,45 @@ type AutocommitRules struct {
 	Rules  string
 	Source string
 	Path   string
+	// Settings come from the file's front matter, if it has any
+	Settings RuleSettings
+	// User is the user rules file merged into the project rules, if any, and UserMerge its merge mode
+	User      string
+	UserMerge string
+	// Layers are the directory rules files layered on top, relative to the repository root
+	Layers []string
 }
 
-func HandleAutoCommit() {
-	// Get OpenAI API key using our new function
-	apiKey, err := config.GetOpenAIAPIKey()
-	if err != nil {
-		fmt.Printf("Error: %v\n", err)
-		fmt.Println("You can reset your configuration by running 'gg config reset'")
-		os.Exit(1)
+func HandleAutoCommit(opts Options) {
+	// With --message-only stdout carries just the message, everything else goes to stderr
+	stdout := os.Stdout
+	if opts.MessageOnly {
+		os.Stdout = os.Stderr
 	}
 
-	if apiKey == "" {
-		fmt.Println("Error: OpenAI API key is required for autocommit")
-		fmt.Println("Please run 'gg config reset' to set up your API key")
-		os.Exit(1)
+	// Non-interactive runs cannot choose between candidates or watch the stream
+	if !opts.interactive() {
+		if opts.Candidates > 1 {
+			fmt.Println("Note: --candidates needs an interactive prompt, generating a single message.")
+			opts.Candidates = 1
+		}
+		opts.NoStream = true
+		if opts.PickCoAuthors {
+			fmt.Println("Note: --pair needs an interactive prompt, use --co-author instead.")
+			opts.PickCoAuthors = false
+		}
 	}
 
-	// Try to validate the key again just to be sure
-	valid, err := config.ValidateAPIKey(apiKey)
-	if !valid {
-		fmt.Printf("Error: The API key is invalid: %v\n", err)
-		fmt.Println("Please run 'gg config reset' to update your API key")
-		os.Exit(1)
+	// Create the configured LLM provider unless running offline
+	var gen generator
+	if !opts.Offline {
+		var err error
+		gen, err = newGenerator(config.CommandAutocommit, opts)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			fmt.Println("You can reset your configuration by running 'gg config reset'")
+			os.Exit(1)
+		}
 	}
 
 	// Get current branch name
@@ -51,34 +66,69 @@ func HandleAutoCommit() {
 		branchName = "unknown"
 	}
 
-	// Get autocommit rules
-	rules, err := getAutocommitRules()
-	if err != nil {
-		fmt.Printf("Warning: Could not load autocommit rules: %v\n", err)
-		rules = AutocommitRules{
-			Rules:  "Please follow the Conventional Commits format: <type>(<scope>): <description>",
-			Source: "root",
-			Path:   "built-in",
+	// Use only the index when staged mode is enabled by flag or config
+	var scope commitScope
+	scope.staged = config.LoadConfig().Staged
+	if opts.Staged != nil {
+		scope.staged = *opts.Staged
+	}
+
+	if opts.Amend {
+		// Amending describes HEAD together with newly staged changes
+		if !git.HasCommits() {
+			fmt.Println("No commit to amend.")
+			os.Exit(1)
+		}
+		if upstream := git.GetPushedUpstream(); upstream != "" && !opts.Force {
+			fmt.Printf("Error: HEAD is already pushed to %s, amending it would rewrite published history.\n", upstream)
+			fmt.Println("Run 'gg ac --amend --force' to amend it anyway.")
+			os.Exit(1)
+		}
+		scope.amendBase, err = git.GetAmendBase()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("Amending the last commit with any staged changes, unstaged and untracked files are left alone.")
+	} else if !git.HasChangesToCommit() {
+		// Check if there are changes to commit
+		fmt.Println("No changes to commit. Working tree clean.")
+		os.Exit(0)
+	} else if scope.staged {
+		if !git.HasStagedChanges() {
+			fmt.Println("No staged changes to commit.")
+			fmt.Println("Stage changes with 'gg add' first, or run 'gg ac --staged=false' to commit everything.")
+			os.Exit(0)
 		}
+		fmt.Println("Using staged changes only, unstaged and untracked files are left alone.")
 	}
 
-	// Check if user has a custom .autocommit.md file
-	currentDir, err := os.Getwd()
+	// Get autocommit rules for the changed paths
+	paths, err := scope.paths()
 	if err != nil {
-		fmt.Printf("Warning: Could not get current directory: %v\n", err)
-	} else {
-		userRulesPath := filepath.Join(currentDir, ".autocommit.md")
-		if _, err := os.Stat(userRulesPath); os.IsNotExist(err) {
-			// Only show the note if no custom .autocommit.md exists
-			fmt.Println("Note: You can customize the commit message format by creating or editing the .autocommit.md file.")
-			fmt.Println("      This file is not tracked by Git (it's in .gitignore).")
-		}
+		fmt.Printf("Warning: Could not get changed paths: %v\n", err)
+	}
+	rules, err := getAutocommitRules(paths)
+	if err != nil {
+		fmt.Printf("Warning: Could not load autocommit rules: %v\n", err)
+		rules = builtinRules()
+	}
+
+	// Only show the note if no custom .autocommit.md exists
+	if rules.Source != "project" && len(rules.Layers) == 0 {
+		fmt.Println("Note: You can customize the commit message format by creating or editing the .autocommit.md file.")
+		fmt.Println("      This file is not tracked by Git (it's in .gitignore).")
 	}
 
 	// Print configuration information
 	fmt.Println("\nCommit Message Configuration:")
 	fmt.Println("===========================")
-	fmt.Printf("Using %s rules from: %s\n", rules.Source, rules.Path)
+	fmt.Printf("Using %s rules from: %s\n", rules.Source, rules.files())
+	if opts.Offline {
+		fmt.Println("Using offline generator (no provider will be contacted)")
+	} else {
+		fmt.Printf("Using model: %s (%s)\n", gen.model(), gen.llm.Name())
+	}
 	fmt.Println()
 
 	// Print branch information
@@ -102,14 +152,8 @@ func HandleAutoCommit() {
 	}
 	fmt.Println()
 
-	// Check if there are changes to commit
-	if !git.HasChangesToCommit() {
-		fmt.Println("No changes to commit. Working tree clean.")
-		os.Exit(0)
-	}
-
 	// Get the diff of changes
-	diff, err := git.GetGitDiff()
+	diff, err := scope.diff()
 	if err != nil {
 		fmt.Printf("Error getting diff: %v\n", err)
 		os.Exit(1)
@@ -121,63 +165,180 @@ func HandleAutoCommit() {
 		os.Exit(0)
 	}
 
-	// Prompt for custom context
-	fmt.Println("\nEnter additional context for the commit message (press Enter to finish):")
-	fmt.Println("(This context will help generate a more relevant commit message)")
-
 	reader := bufio.NewReader(os.Stdin)
-	line, err := reader.ReadString('\n')
+
+	// Keep secrets from leaving the machine (the offline generator sends nothing)
+	if !opts.Offline {
+		var promptReader *bufio.Reader
+		if opts.interactive() {
+			promptReader = reader
+		}
+		screened, err := screenSecrets([]string{diff}, promptReader)
+		if errors.Is(err, errSecretsBlocked) {
+			fmt.Println("Commit canceled, nothing was sent to the provider.")
+			fmt.Println("Remove the secrets, or run 'gg ac --offline' to generate a message locally.")
+			os.Exit(1)
+		}
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		diff = screened[0]
+	}
+
+	// Prompt for custom context unless given as a flag (the offline generator cannot use it)
+	var customContext string
+	if opts.Context != nil {
+		customContext = strings.TrimSpace(*opts.Context)
+	} else if !opts.Offline && opts.interactive() {
+		fmt.Println("\nEnter additional context for the commit message (press Enter to finish):")
+		fmt.Println("(This context will help generate a more relevant commit message)")
+
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			fmt.Printf("Error reading input: %v\n", err)
+			os.Exit(1)
+		}
+
+		customContext = strings.TrimSpace(line)
+	}
+
+	// Ask who worked on the commit, then collect the trailers for the message
+	if opts.PickCoAuthors {
+		err := pickCoAuthors(&opts)
+		if errors.Is(err, errCoAuthorSelectionExited) {
+			fmt.Println("Commit canceled.")
+			os.Exit(0)
+		}
+		if err != nil {
+			fmt.Printf("Warning: Could not pick co-authors: %v\n", err)
+		}
+	}
+	trailers, err := commitTrailers(opts, gen)
 	if err != nil {
-		fmt.Printf("Error reading input: %v\n", err)
+		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
 	}
 
-	customContext := strings.TrimSpace(line)
+	// Put the scope, ticket references and trailers gg knows into every message
+	finish, err := newMessageFinisher(paths, branchName, trailers)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	finish.report()
 
-	// Generate commit message using OpenAI
-	fmt.Println("\nGenerating commit message with AI...")
-	commitMsg, err := generateCommitMessage(apiKey, diff, customContext)
+	// generate produces a commit message with the provider, or from the diff alone when offline.
+	// Retries continue the conversation with the model, passing along the user's feedback.
+	conv := &conversation{}
+	generate := func(retry bool, instruction string) (string, error) {
+		if opts.Offline {
+			message, err := generateOfflineCommitMessage(diff)
+			if err != nil {
+				return "", err
+			}
+			return finish.apply(message), nil
+		}
+		return withInterrupt(func(ctx context.Context) (string, error) {
+			if retry {
+				return conv.refine(ctx, gen, instruction)
+			}
+			if opts.Candidates > 1 {
+				return chooseCommitMessage(ctx, gen, conv, scope, diff, customContext, rules, finish, opts.Candidates)
+			}
+			return generateCommitMessage(ctx, gen, conv, scope, diff, customContext, rules, finish)
+		})
+	}
+
+	// Generate commit message using the configured provider
+	if opts.Offline {
+		if opts.Candidates > 1 {
+			fmt.Println("\nNote: The offline generator produces a single message, ignoring --candidates.")
+		}
+		fmt.Println("\nGenerating commit message offline...")
+	} else if opts.Candidates > 1 {
+		fmt.Printf("\nGenerating %d candidate commit messages with AI...\n", opts.Candidates)
+	} else {
+		fmt.Println("\nGenerating commit message with AI...")
+	}
+	commitMsg, err := generate(false, "")
+	if errors.Is(err, errCandidateSelectionExited) {
+		fmt.Println("Commit canceled.")
+		os.Exit(0)
+	}
+	if errors.Is(err, errGenerationCanceled) {
+		fmt.Println("\nGeneration canceled.")
+		os.Exit(130)
+	}
 	if err != nil {
 		fmt.Printf("Error generating commit message: %v\n", err)
 		fmt.Println("This could be due to an invalid or expired API key.")
 		fmt.Println("Please run 'gg config reset' to update your API key")
+		fmt.Println("or run 'gg ac --offline' to generate a message without a provider")
 		os.Exit(1)
 	}
 
+	// Without a prompt, print or commit the message straight away
+	if opts.MessageOnly {
+		fmt.Fprintln(stdout, commitMsg)
+		return
+	}
+	if opts.DryRun {
+		fmt.Printf("\nGenerated commit message:\n\n%s\n\n", commitMsg)
+		fmt.Println("Dry run, nothing was committed.")
+		return
+	}
+	if opts.Yes {
+		fmt.Printf("\nGenerated commit message:\n\n%s\n\n", commitMsg)
+		if err := scope.commit(commitMsg); err != nil {
+			fmt.Printf("Error committing changes: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Loop to allow retrying commit message generation
 	for {
 		// Display the commit message and ask for confirmation
 		fmt.Printf("\nGenerated commit message:\n\n%s\n\n", commitMsg)
-		fmt.Print("Do you want to commit with this message? (y/n/r=retry): ")
+		fmt.Print("Do you want to commit with this message? (y/n/e=edit/r=retry, or r <feedback>): ")
 
-		response, err := reader.ReadString('\n')
+		line, err := reader.ReadString('\n')
 		if err != nil {
 			fmt.Printf("Error reading input: %v\n", err)
 			os.Exit(1)
 		}
 
-		response = strings.ToLower(strings.TrimSpace(response))
+		response, instruction := parseReply(line)
 
-		if response == "y" || response == "yes" {
-			// Add all changes
-			addCmd := exec.Command("git", "add", ".")
-			addCmd.Stdout = os.Stdout
-			addCmd.Stderr = os.Stderr
-			if err := addCmd.Run(); err != nil {
-				fmt.Printf("Error adding changes: %v\n", err)
-				os.Exit(1)
+		// Edit the message in the git editor, then commit the result
+		if response == "e" || response == "edit" {
+			editedMsg, err := editCommitMessage(commitMsg, scope.diffStat)
+			if err != nil {
+				fmt.Printf("Error editing commit message: %v\n", err)
+				continue
 			}
+			commitMsg = editedMsg
+			response = "y"
+		}
 
-			// Commit changes
-			if err := git.ExecuteGitCommand("commit", "-m", commitMsg); err != nil {
+		if response == "y" || response == "yes" {
+			if err := scope.commit(commitMsg); err != nil {
 				fmt.Printf("Error committing changes: %v\n", err)
 				os.Exit(1)
 			}
 			break
 		} else if response == "r" || response == "retry" {
 			// Regenerate commit message
+			if opts.Offline && instruction != "" {
+				fmt.Println("\nNote: The offline generator cannot use feedback, regenerating without it.")
+			}
 			fmt.Println("\nRegenerating commit message...")
-			newCommitMsg, err := generateCommitMessage(apiKey, diff, customContext)
+			newCommitMsg, err := generate(true, instruction)
+			if errors.Is(err, errGenerationCanceled) {
+				fmt.Println("\nGeneration canceled, keeping the previous message.")
+				continue
+			}
 			if err != nil {
 				fmt.Printf("Error regenerating commit message: %v\n", err)
 				fmt.Println("This could be due to an invalid or expired API key.")
@@ -193,26 +354,16 @@ func HandleAutoCommit() {
 	}
 }
 
-func HandleAutoCommitPerFile() {
-	// Get OpenAI API key using our existing function
-	apiKey, err := config.GetOpenAIAPIKey()
+func HandleAutoCommitPerFile(opts Options) {
+	// Create the configured LLM provider
+	gen, err := newGenerator(config.CommandAutocommitPerFile, opts)
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 		fmt.Println("You can reset your configuration by running 'gg config reset'")
 		os.Exit(1)
 	}
-
-	if apiKey == "" {
-		fmt.Println("Error: OpenAI API key is required for autocommit per file")
-		fmt.Println("Please run 'gg config reset' to set up your API key")
-		os.Exit(1)
-	}
-
-	// Try to validate the key again just to be sure
-	valid, err := config.ValidateAPIKey(apiKey)
-	if !valid {
-		fmt.Printf("Error: The API key is invalid: %v\n", err)
-		fmt.Println("Please run 'gg config reset' to update your API key")
+	if err := config.LoadConfig().Ticket.Validate(); err != nil {
+		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
 	}
 
@@ -223,6 +374,23 @@ func HandleAutoCommitPerFile() {
 	fmt.Println("Use arrow keys to navigate and select files. You can select multiple files interactively.")
 	fmt.Println()
 
+	// Ask who worked on the commits once, every batch gets the same trailers
+	if opts.PickCoAuthors {
+		err := pickCoAuthors(&opts)
+		if errors.Is(err, errCoAuthorSelectionExited) {
+			fmt.Println("Exiting autocommit per file.")
+			return
+		}
+		if err != nil {
+			fmt.Printf("Warning: Could not pick co-authors: %v\n", err)
+		}
+	}
+	trailers, err := commitTrailers(opts, gen)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
 	for {
 		// Get list of changed files
 		changedFiles, err := git.GetChangedFiles()
@@ -266,24 +434,22 @@ func HandleAutoCommitPerFile() {
 		}
 		fmt.Println()
 
-		// Get combined diff for all selected files
-		var combinedDiff strings.Builder
+		// Get the diff of every selected file
+		var fileDiffs []fileDiff
 		var validFiles []string
 
 		for _, file := range selectedFiles {
-			fileDiff, err := git.GetFileDiff(file)
+			diff, err := git.GetFileDiff(file)
 			if err != nil {
 				fmt.Printf("Warning: Could not get diff for %s: %v\n", file, err)
 				continue
 			}
-			if fileDiff == "" {
+			if diff == "" {
 				fmt.Printf("Warning: No changes detected in %s, skipping.\n", file)
 				continue
 			}
 
-			combinedDiff.WriteString(fmt.Sprintf("--- %s ---\n", file))
-			combinedDiff.WriteString(fileDiff)
-			combinedDiff.WriteString("\n")
+			fileDiffs = append(fileDiffs, fileDiff{Path: file, Diff: diff + "\n"})
 			validFiles = append(validFiles, file)
 		}
 
@@ -292,6 +458,24 @@ func HandleAutoCommitPerFile() {
 			continue
 		}
 
+		// Keep secrets from leaving the machine
+		diffs := make([]string, len(fileDiffs))
+		for i, file := range fileDiffs {
+			diffs[i] = file.Diff
+		}
+		screened, err := screenSecrets(diffs, reader)
+		if errors.Is(err, errSecretsBlocked) {
+			fmt.Println("Skipped batch, nothing was sent to the provider.")
+			continue
+		}
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			continue
+		}
+		for i := range fileDiffs {
+			fileDiffs[i].Diff = screened[i]
+		}
+
 		// Ask for custom context for the batch
 		fmt.Printf("Enter additional context for these %d file(s) (press Enter to skip): ", len(validFiles))
 		contextLine, err := reader.ReadString('\n')
@@ -301,9 +485,12 @@ func HandleAutoCommitPerFile() {
 		}
 		customContext := strings.TrimSpace(contextLine)
 
-		// Generate commit message for the batch
+		// Generate commit message for the batch, keeping the conversation for retries
 		fmt.Printf("Generating commit message for %d file(s)...\n", len(validFiles))
-		commitMsg, err := generateBatchCommitMessage(apiKey, validFiles, combinedDiff.String(), customContext)
+		conv := &conversation{}
+		commitMsg, err := withInterrupt(func(ctx context.Context) (string, error) {
+			return generateBatchCommitMessage(ctx, gen, conv, fileDiffs, customContext, trailers)
+		})
 		if err != nil {
 			fmt.Printf("Error generating commit message for batch: %v\n", err)
 			continue
@@ -313,14 +500,27 @@ func HandleAutoCommitPerFile() {
 		for {
 			// Display the commit message and ask for confirmation
 			fmt.Printf("\nGenerated commit message for batch:\n\n%s\n\n", commitMsg)
-			fmt.Print("Do you want to commit these files with this message? (y/n/r=retry/exit): ")
+			fmt.Print("Do you want to commit these files with this message? (y/n/e=edit/r=retry, or r <feedback>/exit): ")
 
-			response, err := reader.ReadString('\n')
+			line, err := reader.ReadString('\n')
 			if err != nil {
 				fmt.Printf("Error reading input: %v\n", err)
 				continue
 			}
-			response = strings.ToLower(strings.TrimSpace(response))
+			response, instruction := parseReply(line)
+
+			// Edit the message in the git editor, then commit the result
+			if response == "e" || response == "edit" {
+				editedMsg, err := editCommitMessage(commitMsg, func() (string, error) {
+					return git.GetDiffStat(validFiles...)
+				})
+				if err != nil {
+					fmt.Printf("Error editing commit message: %v\n", err)
+					continue
+				}
+				commitMsg = editedMsg
+				response = "y"
+			}
 
 			if response == "exit" {
 				fmt.Println("Exiting autocommit per file.")
@@ -349,7 +549,9 @@ func HandleAutoCommitPerFile() {
 			} else if response == "r" || response == "retry" {
 				// Regenerate commit message for the batch
 				fmt.Printf("Regenerating commit message for %d file(s)...\n", len(validFiles))
-				newCommitMsg, err := generateBatchCommitMessage(apiKey, validFiles, combinedDiff.String(), customContext)
+				newCommitMsg, err := withInterrupt(func(ctx context.Context) (string, error) {
+					return conv.refine(ctx, gen, instruction)
+				})
 				if err != nil {
 					fmt.Printf("Error regenerating commit message for batch: %v\n", err)
 					continue
@@ -377,10 +579,7 @@ func HandleAutoCommitPerFile() {
 	}
 }
 
-func generateBatchCommitMessage(apiKey string, filenames []string, combinedDiff, customContext string) (string, error) {
-	// Initialize OpenAI client
-	client := openai.NewClient(apiKey)
-
+func generateBatchCommitMessage(ctx context.Context, gen generator, conv *conversation, files []fileDiff, customContext string, trailers []string) (string, error) {
 	// Get current branch name
 	branchName, err := git.GetCurrentBranch()
 	if err != nil {
@@ -388,72 +587,62 @@ func generateBatchCommitMessage(apiKey string, filenames []string, combinedDiff,
 		branchName = "unknown"
 	}
 
-	// Truncate diff if it's too large (OpenAI has token limits)
-	maxDiffLength := 4000
-	diffContent := combinedDiff
-	if len(combinedDiff) > maxDiffLength {
-		diffContent = combinedDiff[:maxDiffLength] + "\n...(diff truncated due to size)"
+	// Create file list string
+	filenames := make([]string, len(files))
+	var combinedDiff strings.Builder
+	for i, file := range files {
+		filenames[i] = file.Path
+		combinedDiff.WriteString(file.Path + "\n" + file.Diff)
 	}
+	fileListStr := strings.Join(filenames, ", ")
 
-	// Get autocommit rules
-	rules, err := getAutocommitRules()
+	// Get autocommit rules for these files
+	rules, err := getAutocommitRules(filenames)
 	if err != nil {
 		fmt.Printf("Warning: Could not load autocommit rules: %v\n", err)
-		rules = AutocommitRules{
-			Rules:  "Please follow the Conventional Commits format: <type>(<scope>): <description>",
-			Source: "root",
-			Path:   "built-in",
-		}
+		rules = builtinRules()
 	}
-
-	// Create file list string
-	fileListStr := strings.Join(filenames, ", ")
-
-	// Create prompt for OpenAI focused on the batch of files
-	prompt := fmt.Sprintf(
-		"Generate a commit message for changes to these %d files: %s\n\n"+
-			"Combined git diff for these files:\n%s\n\n"+
-			"Current branch: %s\n\n"+
-			"Additional context provided by the user:\n%s\n\n"+
-			"Must follow these rules for the commit message:\n%s\n\n"+
-			"Create a unified commit message that summarizes the changes across all these files. "+
-			"Reply with ONLY the commit message, nothing else.",
-		len(filenames),
-		fileListStr,
-		diffContent,
-		branchName,
-		customContext,
-		rules.Rules,
-	)
-
-	// Create chat completion request
-	resp, err := client.CreateChatCompletion(
-		context.Background(),
-		openai.ChatCompletionRequest{
-			Model: openai.GPT4Dot1Nano,
-			Messages: []openai.ChatCompletionMessage{
-				{
-					Role:    openai.ChatMessageRoleUser,
-					Content: prompt,
-				},
-			},
-			MaxTokens: 250,
-		},
-	)
-
+	fmt.Printf("Using %s rules from: %s\n", rules.Source, rules.files())
+	finish, err := newMessageFinisher(filenames, branchName, trailers)
 	if err != nil {
-		return "", fmt.Errorf("chat completion error: %v", err)
+		return "", err
 	}
+	finish.report()
 
-	// Extract the commit message from the response
-	commitMessage := resp.Choices[0].Message.Content
-	return strings.TrimSpace(commitMessage), nil
-}
+	// Reuse a cached message for the same changes, rules and context
+	key := messageCacheKey(gen, config.CommandAutocommitPerFile, combinedDiff.String(), rules.guidance()+finish.guidance(), branchName, customContext)
+	conv.start(key, generationPolicy(rules), finish, func(ctx context.Context) (string, error) {
+		// Fit the diffs into the token budget, summarizing them first when far too large
+		diffContent, err := prepareDiff(ctx, gen, files, "")
+		if err != nil {
+			return "", err
+		}
 
-func generateFileCommitMessage(apiKey, filename, diff, customContext string) (string, error) {
-	// Initialize OpenAI client
-	client := openai.NewClient(apiKey)
+		// Create prompt for the model focused on the batch of files
+		prompt := fmt.Sprintf(
+			"Generate a commit message for changes to these %d files: %s\n\n"+
+				"Combined git diff for these files:\n%s\n\n"+
+				"Current branch: %s\n\n"+
+				"Additional context provided by the user:\n%s\n\n"+
+				"Must follow these rules for the commit message:\n%s\n\n"+
+				"Create a unified commit message that summarizes the changes across all these files. "+
+				"Reply with ONLY the commit message, nothing else.",
+			len(filenames),
+			fileListStr,
+			diffContent,
+			branchName,
+			customContext,
+			rules.guidance()+finish.guidance(),
+		)
+
+		return prompt, nil
+	})
+
+	// Send the prompt to the provider
+	return conv.generate(ctx, gen)
+}
 
+func generateFileCommitMessage(ctx context.Context, gen generator, filename, diff, customContext string) (string, error) {
 	// Get current branch name
 	branchName, err := git.GetCurrentBranch()
 	if err != nil {
@@ -461,25 +650,21 @@ func generateFileCommitMessage(apiKey, filename, diff, customContext string) (st
 		branchName = "unknown"
 	}
 
-	// Truncate diff if it's too large (OpenAI has token limits)
-	maxDiffLength := 3000
-	diffContent := diff
-	if len(diff) > maxDiffLength {
-		diffContent = diff[:maxDiffLength] + "\n...(diff truncated due to size)"
-	}
+	// Fit the diff into the token budget
+	diffContent := budgetDiff([]fileDiff{{Path: filename, Diff: diff}}, "", gen.settings.DiffTokens)
 
 	// Get autocommit rules
-	rules, err := getAutocommitRules()
+	rules, err := getAutocommitRules([]string{filename})
 	if err != nil {
 		fmt.Printf("Warning: Could not load autocommit rules: %v\n", err)
-		rules = AutocommitRules{
-			Rules:  "Please follow the Conventional Commits format: <type>(<scope>): <description>",
-			Source: "root",
-			Path:   "built-in",
-		}
+		rules = builtinRules()
+	}
+	finish, err := newMessageFinisher([]string{filename}, branchName, nil)
+	if err != nil {
+		return "", err
 	}
 
-	// Create prompt for OpenAI focused on the specific file
+	// Create prompt for the model focused on the specific file
 	prompt := fmt.Sprintf(
 		"Generate a commit message for changes to this specific file: %s\n\n"+
 			"Git diff for this file:\n%s\n\n"+
@@ -492,80 +677,53 @@ func generateFileCommitMessage(apiKey, filename, diff, customContext string) (st
 		diffContent,
 		branchName,
 		customContext,
-		rules.Rules,
-	)
-
-	// Create chat completion request
-	resp, err := client.CreateChatCompletion(
-		context.Background(),
-		openai.ChatCompletionRequest{
-			Model: openai.GPT4Dot1Nano,
-			Messages: []openai.ChatCompletionMessage{
-				{
-					Role:    openai.ChatMessageRoleUser,
-					Content: prompt,
-				},
-			},
-			MaxTokens: 200,
-		},
+		rules.guidance()+finish.guidance(),
 	)
 
+	// Send the prompt to the provider
+	message, err := gen.complete(ctx, prompt)
 	if err != nil {
-		return "", fmt.Errorf("chat completion error: %v", err)
+		return "", err
 	}
-
-	// Extract the commit message from the response
-	commitMessage := resp.Choices[0].Message.Content
-	return strings.TrimSpace(commitMessage), nil
+	return finish.apply(message), nil
 }
 
-func getAutocommitRules() (AutocommitRules, error) {
-	// Get current working directory
-	currentDir, err := os.Getwd()
+// chooseCommitMessage generates several candidate messages and lets the user
+// pick one, optionally editing it. The chosen message continues the conversation.
+func chooseCommitMessage(ctx context.Context, gen generator, conv *conversation, scope commitScope, diff, customContext string, rules AutocommitRules, finish messageFinisher, count int) (string, error) {
+	startCommitConversation(gen, conv, scope, diff, customContext, rules, finish)
+
+	candidates, err := conv.candidates(ctx, gen, count)
 	if err != nil {
-		return AutocommitRules{}, fmt.Errorf("error getting current directory: %v", err)
+		return "", err
 	}
-
-	// First, check for user's .autocommit.md in project root
-	userRulesPath := filepath.Join(currentDir, ".autocommit.md")
-	content, err := os.ReadFile(userRulesPath)
-	if err == nil {
-		return AutocommitRules{
-			Rules:  string(content),
-			Source: "project",
-			Path:   userRulesPath,
-		}, nil
+	if len(candidates) < count {
+		fmt.Printf("Note: Only %d of %d candidates were distinct.\n", len(candidates), count)
 	}
 
-	// If not found in project root, check executable directory for default rules
-	exePath, err := os.Executable()
+	fmt.Println()
+	commitMsg, err := ui.SelectCommitMessage(candidates)
 	if err != nil {
-		return AutocommitRules{}, fmt.Errorf("error getting executable path: %v", err)
-	}
-
-	exeDir := filepath.Dir(exePath)
-	defaultRulesPath := filepath.Join(exeDir, ".autocommit.md")
-	content, err = os.ReadFile(defaultRulesPath)
-	if err == nil {
-		return AutocommitRules{
-			Rules:  string(content),
-			Source: "default",
-			Path:   defaultRulesPath,
-		}, nil
-	}
-
-	// Default rules if no .autocommit.md is found anywhere
-	return AutocommitRules{
-		Rules:  "Please follow the Conventional Commits format: <type>(<scope>): <description>",
-		Source: "root",
-		Path:   "built-in",
-	}, nil
+		if strings.Contains(err.Error(), "user chose to exit") {
+			return "", errCandidateSelectionExited
+		}
+		return "", err
+	}
+
+	conv.choose(gen, commitMsg)
+	return commitMsg, nil
 }
 
-func generateCommitMessage(apiKey, diff string, customContext string) (string, error) {
-	// Initialize OpenAI client
-	client := openai.NewClient(apiKey)
+func generateCommitMessage(ctx context.Context, gen generator, conv *conversation, scope commitScope, diff string, customContext string, rules AutocommitRules, finish messageFinisher) (string, error) {
+	startCommitConversation(gen, conv, scope, diff, customContext, rules, finish)
 
+	// Send the prompt to the provider
+	return conv.generate(ctx, gen)
+}
+
+// startCommitConversation starts conv with the prompt for the whole diff,
+// following rules. finish is applied to every message the model writes.
+func startCommitConversation(gen generator, conv *conversation, scope commitScope, diff string, customContext string, rules AutocommitRules, finish messageFinisher) {
 	// Get current branch name
 	branchName, err := git.GetCurrentBranch()
 	if err != nil {
@@ -573,66 +731,45 @@ func generateCommitMessage(apiKey, diff string, customContext string) (string, e
 		branchName = "unknown"
 	}
 
-	// Get last commit metadata
-	lastCommitInfo, err := git.GetLastCommitMetadata()
-	if err != nil {
-		fmt.Printf("Warning: Could not get last commit metadata: %v\n", err)
-		lastCommitInfo = ""
+	// Get last commit metadata. When amending, HEAD is the commit being
+	// rewritten, so its parent is the previous commit; a root commit has none.
+	var lastCommitInfo string
+	var metadataErr error
+	if scope.amendBase == "" {
+		lastCommitInfo, metadataErr = git.GetLastCommitMetadata()
+	} else if scope.amendBase == "HEAD~1" {
+		lastCommitInfo, metadataErr = git.GetParentCommitMetadata()
 	}
-
-	// Truncate diff if it's too large (OpenAI has token limits)
-	maxDiffLength := 4000
-	diffContent := diff
-	if len(diff) > maxDiffLength {
-		diffContent = diff[:maxDiffLength] + "\n...(diff truncated due to size)"
+	if metadataErr != nil {
+		fmt.Printf("Warning: Could not get last commit metadata: %v\n", metadataErr)
+		lastCommitInfo = ""
 	}
 
-	// Get autocommit rules
-	rules, err := getAutocommitRules()
-	if err != nil {
-		fmt.Printf("Warning: Could not load autocommit rules: %v\n", err)
-		rules = AutocommitRules{
-			Rules:  "Please follow the Conventional Commits format: <type>(<scope>): <description>",
-			Source: "root",
-			Path:   "built-in",
+	// Reuse a cached message for the same changes, rules and context
+	key := messageCacheKey(gen, config.CommandAutocommit, diff, rules.guidance()+finish.guidance(), branchName, customContext)
+	conv.start(key, generationPolicy(rules), finish, func(ctx context.Context) (string, error) {
+		// Fit the diff into the token budget, summarizing it first when far too large
+		files, trailer := splitGitDiff(diff)
+		diffContent, err := prepareDiff(ctx, gen, files, trailer)
+		if err != nil {
+			return "", err
 		}
-	}
-
-	// Create prompt for OpenAI
-	prompt := fmt.Sprintf(
-		"Generate a commit message for the following git diff:\n\n%s\n\n"+
-			"Current branch: %s\n"+
-			"%s\n\n"+
-			"Additional context provided by the user:\n%s\n\n"+
-			"Must follow these rules for the commit message:\n%s\n\n"+
-			"Reply with ONLY the commit message, nothing else.",
-		diffContent,
-		branchName,
-		lastCommitInfo,
-		customContext,
-		rules.Rules,
-	)
-
-	// Create chat completion request
-	resp, err := client.CreateChatCompletion(
-		context.Background(),
-		openai.ChatCompletionRequest{
-			Model: openai.GPT4Dot1Nano,
-			Messages: []openai.ChatCompletionMessage{
-				{
-					Role:    openai.ChatMessageRoleUser,
-					Content: prompt,
-				},
-			},
-			MaxTokens: 250,
-		},
-	)
-
-	if err != nil {
-		return "", fmt.Errorf("chat completion error: %v", err)
-	}
 
-	// Extract the commit message from the response
-	commitMessage := resp.Choices[0].Message.Content
-	return strings.TrimSpace(commitMessage), nil
+		// Create prompt for the model
+		prompt := fmt.Sprintf(
+			"Generate a commit message for the following git diff:\n\n%s\n\n"+
+				"Current branch: %s\n"+
+				"%s\n\n"+
+				"Additional context provided by the user:\n%s\n\n"+
+				"Must follow these rules for the commit message:\n%s\n\n"+
+				"Reply with ONLY the commit message, nothing else.",
+			diffContent,
+			branchName,
+			lastCommitInfo,
+			customContext,
+			rules.guidance()+finish.guidance(),
+		)
+
+		return prompt, nil
+	})
 }
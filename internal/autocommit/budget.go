@@ -0,0 +1,459 @@
+package autocommit
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Line priorities used when a diff has to be shortened. Lower values are
+// kept first: every file keeps its header before any file gets hunk bodies.
+const (
+	priorityHeader = iota
+	priorityHunk
+	prioritySignature
+	priorityChange
+	priorityContext
+	// priorityNoise lines are dropped whenever a diff has to be shortened
+	priorityNoise
+)
+
+// summaryReserve is the number of tokens kept free for the omitted-lines summary
+const summaryReserve = 30
+
+// minTruncatedTokens is the least room a line that does not fit whole is cut
+// down to. A shorter cut would show too little of the line to be useful.
+const minTruncatedTokens = 8
+
+// maxBudgetPasses limits how often allowance files could not use is handed on
+const maxBudgetPasses = 4
+
+// truncationMark ends a line that was cut to fit the budget
+const truncationMark = "…"
+
+// gapMarker stands in for omitted lines
+const gapMarker = " ...\n"
+
+// signaturePattern matches lines that declare something (functions, types,
+// classes, ...) in the common languages. These say the most about a change.
+var signaturePattern = regexp.MustCompile(`^\s*(func|type|class|def|interface|struct|enum|impl|fn|pub|export|module|package|public|private|protected|static|async|function|const|var|let)\b`)
+
+// fileDiff is the diff of a single file
+type fileDiff struct {
+	Path string
+	Diff string
+}
+
+// diffLine is a single diff line with its budgeting priority
+type diffLine struct {
+	text     string
+	priority int
+	tokens   int
+	added    bool
+	removed  bool
+}
+
+// estimateTokens approximates the number of model tokens in s. Roughly four
+// bytes per token holds well enough for code and English prose.
+func estimateTokens(s string) int {
+	return (len(s) + 3) / 4
+}
+
+// splitGitDiff splits combined `git diff` output into one entry per file.
+// Sections for the same path (staged and unstaged) are merged, untracked
+// files ("New file:" sections) get an entry of their own, and any text that
+// is not part of a file diff is returned as the trailer.
+func splitGitDiff(diff string) ([]fileDiff, string) {
+	var paths []string
+	var bodies []*strings.Builder
+	index := make(map[string]int)
+	var trailer strings.Builder
+	current := -1
+
+	for _, line := range strings.SplitAfter(diff, "\n") {
+		if strings.HasPrefix(line, "diff --git ") || strings.HasPrefix(line, "New file: ") {
+			header := strings.TrimRight(line, "\n")
+			path := strings.TrimPrefix(header, "New file: ")
+			if idx := strings.LastIndex(header, " b/"); idx != -1 && strings.HasPrefix(header, "diff --git ") {
+				path = header[idx+3:]
+			}
+			if i, ok := index[path]; ok {
+				current = i
+			} else {
+				paths = append(paths, path)
+				bodies = append(bodies, &strings.Builder{})
+				current = len(paths) - 1
+				index[path] = current
+			}
+		}
+
+		if current >= 0 {
+			bodies[current].WriteString(line)
+		} else {
+			trailer.WriteString(line)
+		}
+	}
+
+	files := make([]fileDiff, len(paths))
+	for i, path := range paths {
+		files[i] = fileDiff{Path: path, Diff: bodies[i].String()}
+	}
+
+	return files, trailer.String()
+}
+
+// budgetDiff renders the file diffs within maxTokens. When everything fits
+// the diff is returned unchanged. Otherwise each file gets a fair share of
+// the budget, filled with its header, hunk headers and signature lines
+// before ordinary changed and context lines, and a summary of what had to
+// be omitted is appended. Allowance a file cannot use is handed on to the
+// files that need more. A line too long for the room left is cut on a rune
+// boundary, so every file shown keeps at least the start of a change.
+func budgetDiff(files []fileDiff, trailer string, maxTokens int) string {
+	total := estimateTokens(trailer)
+	for _, file := range files {
+		total += estimateTokens(file.Diff)
+	}
+	if total <= maxTokens {
+		var out strings.Builder
+		for _, file := range files {
+			out.WriteString(file.Diff)
+		}
+		out.WriteString(trailer)
+		return out.String()
+	}
+
+	// The trailer (e.g. untracked file names) may use at most a quarter of the budget
+	trailer = truncateLines(trailer, maxTokens/4)
+	budget := maxTokens - estimateTokens(trailer) - summaryReserve
+	if budget < 0 {
+		budget = 0
+	}
+
+	selections := make([]*lineSelection, len(files))
+	for i, file := range files {
+		selections[i] = newLineSelection(classifyDiffLines(file.Diff))
+	}
+
+	// Small files and lines too long for a share leave allowance unused,
+	// the next pass spreads it over the files that still need room
+	remaining := budget
+	for pass := 0; pass < maxBudgetPasses && remaining > 0; pass++ {
+		needs := make([]int, len(selections))
+		for i, selection := range selections {
+			needs[i] = selection.needed()
+		}
+
+		allowances := fairShare(needs, remaining)
+		progress := false
+		for i, selection := range selections {
+			cost := selection.cost()
+			selection.extend(cost + allowances[i])
+			if selection.cost() > cost {
+				remaining -= selection.cost() - cost
+				progress = true
+			}
+		}
+		if !progress {
+			break
+		}
+	}
+
+	var out strings.Builder
+	omittedFiles, omittedAdded, omittedRemoved := 0, 0, 0
+	for _, selection := range selections {
+		rendered, added, removed, ok := selection.render()
+		omittedAdded += added
+		omittedRemoved += removed
+		if !ok {
+			omittedFiles++
+			continue
+		}
+		out.WriteString(rendered)
+	}
+	out.WriteString(trailer)
+
+	if omittedFiles > 0 || omittedAdded > 0 || omittedRemoved > 0 {
+		out.WriteString(fmt.Sprintf("\n...(omitted: %d files, +%d/-%d lines)\n", omittedFiles, omittedAdded, omittedRemoved))
+	}
+
+	return out.String()
+}
+
+// classifyDiffLines assigns a budgeting priority to every line of a file diff
+func classifyDiffLines(diff string) []diffLine {
+	var lines []diffLine
+	inHunk, inContent := false, false
+
+	for _, text := range strings.SplitAfter(diff, "\n") {
+		if text == "" {
+			continue
+		}
+		line := diffLine{text: text, tokens: estimateTokens(text), priority: priorityContext}
+
+		switch {
+		case strings.HasPrefix(text, "diff --git "):
+			inHunk, inContent = false, false
+			line.priority = priorityHeader
+		case strings.HasPrefix(text, "New file: "):
+			inHunk, inContent = false, false
+			line.priority = priorityHeader
+		case strings.HasPrefix(text, "File content:"):
+			// Content of an untracked file counts as added lines
+			inContent = true
+			line.priority = priorityHunk
+		case inContent:
+			line.added = true
+			line.priority = priorityChange
+			if signaturePattern.MatchString(text) {
+				line.priority = prioritySignature
+			}
+		case strings.HasPrefix(text, "@@"):
+			inHunk = true
+			line.priority = priorityHunk
+		case !inHunk:
+			// Mode, rename and binary notes describe the change; index and
+			// ---/+++ lines only repeat what the diff --git line says
+			line.priority = priorityHeader
+			if strings.HasPrefix(text, "index ") || strings.HasPrefix(text, "--- ") || strings.HasPrefix(text, "+++ ") {
+				line.priority = priorityNoise
+			}
+		case strings.HasPrefix(text, "+"), strings.HasPrefix(text, "-"):
+			line.added = text[0] == '+'
+			line.removed = text[0] == '-'
+			line.priority = priorityChange
+			if signaturePattern.MatchString(text[1:]) {
+				line.priority = prioritySignature
+			}
+		}
+
+		lines = append(lines, line)
+	}
+
+	return lines
+}
+
+// fairShare splits budget across items with the given costs. Items that need
+// less than an even share get everything they need, and the leftover is
+// spread over the remaining, larger items.
+func fairShare(costs []int, budget int) []int {
+	order := make([]int, len(costs))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return costs[order[a]] < costs[order[b]]
+	})
+
+	allowances := make([]int, len(costs))
+	remaining := budget
+	for k, i := range order {
+		share := remaining / (len(order) - k)
+		allowances[i] = min(costs[i], share)
+		remaining -= allowances[i]
+	}
+
+	return allowances
+}
+
+// lineSelection is the part of a file diff kept within its allowance
+type lineSelection struct {
+	lines []diffLine
+	keep  []bool
+	// prev and next are the closest lines on either side that are rendered
+	// when kept, noise lines are skipped
+	prev, next []int
+	// used is the tokens of the kept lines
+	used int
+	// gaps is the number of gap markers rendering the kept lines takes
+	gaps int
+	// header is set once the file header is kept, files are shown with their
+	// whole header or not at all
+	header bool
+}
+
+func newLineSelection(lines []diffLine) *lineSelection {
+	s := &lineSelection{
+		lines: lines,
+		keep:  make([]bool, len(lines)),
+		prev:  make([]int, len(lines)),
+		next:  make([]int, len(lines)),
+	}
+
+	last := -1
+	for i, line := range lines {
+		s.prev[i] = last
+		if line.priority != priorityNoise {
+			last = i
+		}
+	}
+	// Nothing kept yet is a single gap
+	if last != -1 {
+		s.gaps = 1
+	}
+	last = len(lines)
+	for i := len(lines) - 1; i >= 0; i-- {
+		s.next[i] = last
+		if lines[i].priority != priorityNoise {
+			last = i
+		}
+	}
+	return s
+}
+
+// cost returns the tokens the rendered selection takes
+func (s *lineSelection) cost() int {
+	if !s.header {
+		return 0
+	}
+	return s.used + s.gaps*estimateTokens(gapMarker)
+}
+
+// needed returns the tokens of the lines that are not kept yet
+func (s *lineSelection) needed() int {
+	needed := 0
+	for i, line := range s.lines {
+		if !s.keep[i] && line.priority != priorityNoise {
+			needed += line.tokens
+		}
+	}
+	return needed
+}
+
+// gapChange returns how keeping line i changes the number of gap markers:
+// it splits a gap between two omitted neighbors and closes one between two
+// kept neighbors. The ends of the file count as kept.
+func (s *lineSelection) gapChange(i int) int {
+	kept := func(j int) bool { return j < 0 || j >= len(s.lines) || s.keep[j] }
+	before, after := kept(s.prev[i]), kept(s.next[i])
+	switch {
+	case !before && !after:
+		return 1
+	case before && after:
+		return -1
+	default:
+		return 0
+	}
+}
+
+// add keeps line i
+func (s *lineSelection) add(i int) {
+	s.gaps += s.gapChange(i)
+	s.keep[i] = true
+	s.used += s.lines[i].tokens
+}
+
+// extend keeps more lines, highest priority first, while the selection costs
+// at most allowance tokens. A line that does not fit whole is cut to the
+// room left when that is at least minTruncatedTokens.
+func (s *lineSelection) extend(allowance int) {
+	if !s.header {
+		for i, line := range s.lines {
+			if line.priority == priorityHeader {
+				s.add(i)
+			}
+		}
+		s.header = true
+		if s.cost() > allowance {
+			*s = *newLineSelection(s.lines)
+			return
+		}
+	}
+
+	gapTokens := estimateTokens(gapMarker)
+	for priority := priorityHunk; priority <= priorityContext; priority++ {
+		for i, line := range s.lines {
+			if s.keep[i] || line.priority != priority {
+				continue
+			}
+			room := allowance - s.cost() - s.gapChange(i)*gapTokens
+			if line.tokens > room {
+				if room < minTruncatedTokens {
+					continue
+				}
+				line.text = truncateLine(line.text, room)
+				line.tokens = estimateTokens(line.text)
+				s.lines[i] = line
+			}
+			s.add(i)
+		}
+	}
+}
+
+// render writes the kept lines in their original order, marking gaps with an
+// ellipsis. It returns the number of added and removed lines left out, and
+// false when not even the file header fit.
+func (s *lineSelection) render() (string, int, int, bool) {
+	if !s.header {
+		return "", countChanges(s.lines, nil, true), countChanges(s.lines, nil, false), false
+	}
+
+	var out strings.Builder
+	skipping := false
+	for i, line := range s.lines {
+		if s.keep[i] {
+			out.WriteString(line.text)
+			skipping = false
+		} else if line.priority == priorityNoise {
+			continue
+		} else if !skipping {
+			out.WriteString(gapMarker)
+			skipping = true
+		}
+	}
+
+	return out.String(), countChanges(s.lines, s.keep, true), countChanges(s.lines, s.keep, false), true
+}
+
+// truncateLine cuts line down to at most maxTokens on a rune boundary and
+// marks the cut
+func truncateLine(line string, maxTokens int) string {
+	line = strings.TrimSuffix(line, "\n")
+	limit := maxTokens*4 - len(truncationMark) - 1
+	if limit < 0 {
+		limit = 0
+	}
+	if limit >= len(line) {
+		return line + "\n"
+	}
+	for limit > 0 && !utf8.RuneStart(line[limit]) {
+		limit--
+	}
+	return line[:limit] + truncationMark + "\n"
+}
+
+// countChanges counts added (or removed) lines that are not kept
+func countChanges(lines []diffLine, keep []bool, added bool) int {
+	count := 0
+	for i, line := range lines {
+		if keep != nil && keep[i] {
+			continue
+		}
+		if (added && line.added) || (!added && line.removed) {
+			count++
+		}
+	}
+	return count
+}
+
+// truncateLines keeps whole lines of s up to maxTokens
+func truncateLines(s string, maxTokens int) string {
+	if estimateTokens(s) <= maxTokens {
+		return s
+	}
+
+	var out strings.Builder
+	used := 0
+	for _, line := range strings.SplitAfter(s, "\n") {
+		tokens := estimateTokens(line)
+		if used+tokens > maxTokens {
+			out.WriteString("...(list truncated due to size)\n")
+			break
+		}
+		out.WriteString(line)
+		used += tokens
+	}
+	return out.String()
+}
@@ -0,0 +1,120 @@
+package autocommit
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// fileWithChange returns a one-hunk diff of path replacing a line with another
+func fileWithChange(path, removed, added string) fileDiff {
+	return fileDiff{Path: path, Diff: fmt.Sprintf(
+		"diff --git a/%s b/%s\nindex 1111111..2222222 100644\n--- a/%s\n+++ b/%s\n@@ -1 +1 @@\n-%s\n+%s\n",
+		path, path, path, path, removed, added,
+	)}
+}
+
+func TestBudgetDiffManyFilesWithLongLines(t *testing.T) {
+	// 50 files whose only changes are 400-character lines, far over a 3000 token budget
+	var files []fileDiff
+	for i := 0; i < 50; i++ {
+		files = append(files, fileWithChange(
+			fmt.Sprintf("pkg/file%02d.go", i),
+			strings.Repeat("o", 400),
+			strings.Repeat("n", 400),
+		))
+	}
+
+	out := budgetDiff(files, "", 3000)
+
+	if tokens := estimateTokens(out); tokens > 3000 {
+		t.Errorf("budgeted diff is %d tokens, over the budget of 3000", tokens)
+	} else if tokens < 2500 {
+		t.Errorf("budgeted diff is only %d tokens, the unused allowance was not handed on", tokens)
+	}
+
+	for _, file := range files {
+		section := out[strings.Index(out, "diff --git a/"+file.Path):]
+		if next := strings.Index(section[1:], "diff --git "); next != -1 {
+			section = section[:next+1]
+		}
+		if !strings.Contains(section, "\n-ooo") {
+			t.Errorf("%s lost its first changed line:\n%s", file.Path, section)
+		}
+	}
+
+	if !strings.Contains(out, "...(omitted: 0 files,") {
+		t.Errorf("summary should report every file as shown:\n%s", out[len(out)-80:])
+	}
+	if strings.Contains(out, "+0/-50 lines") || strings.Contains(out, "+50/-50 lines") {
+		t.Errorf("every removed line was omitted:\n%s", out[len(out)-80:])
+	}
+}
+
+func TestBudgetDiffHandsOnUnusedAllowance(t *testing.T) {
+	// One tiny file and one large one: the large file gets what the tiny one leaves
+	small := fileWithChange("small.go", "a", "b")
+	var removed, added []string
+	for i := 0; i < 200; i++ {
+		removed = append(removed, fmt.Sprintf("-old line %03d with some words", i))
+		added = append(added, fmt.Sprintf("+new line %03d with some words", i))
+	}
+	large := fileDiff{Path: "large.go", Diff: "diff --git a/large.go b/large.go\n@@ -1,200 +1,200 @@\n" +
+		strings.Join(removed, "\n") + "\n" + strings.Join(added, "\n") + "\n"}
+
+	out := budgetDiff([]fileDiff{small, large}, "", 1000)
+
+	if !strings.Contains(out, "-a\n+b\n") {
+		t.Errorf("small file should be shown whole:\n%s", out)
+	}
+	if tokens := estimateTokens(out); tokens < 900 || tokens > 1000 {
+		t.Errorf("budgeted diff is %d tokens, want close to the budget of 1000", tokens)
+	}
+}
+
+func TestBudgetDiffFitsUnchanged(t *testing.T) {
+	files := []fileDiff{fileWithChange("a.go", "x", "y")}
+	if out := budgetDiff(files, "trailer\n", 1000); out != files[0].Diff+"trailer\n" {
+		t.Errorf("diff within the budget was changed:\n%s", out)
+	}
+}
+
+func TestTruncateLine(t *testing.T) {
+	tests := []struct {
+		name      string
+		line      string
+		maxTokens int
+		want      string
+	}{
+		{name: "fits", line: "+short\n", maxTokens: 8, want: "+short\n"},
+		{name: "ascii", line: "+" + strings.Repeat("a", 100) + "\n", maxTokens: 8, want: "+" + strings.Repeat("a", 27) + "…\n"},
+		{name: "multi-byte", line: "+" + strings.Repeat("é", 50) + "\n", maxTokens: 8, want: "+" + strings.Repeat("é", 13) + "…\n"},
+		{name: "no room", line: "+abc\n", maxTokens: 0, want: "…\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := truncateLine(tt.line, tt.maxTokens)
+			if got != tt.want {
+				t.Errorf("truncateLine = %q, want %q", got, tt.want)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("truncateLine split a rune: %q", got)
+			}
+			if tt.maxTokens > 0 && estimateTokens(got) > tt.maxTokens {
+				t.Errorf("truncateLine = %d tokens, want at most %d", estimateTokens(got), tt.maxTokens)
+			}
+		})
+	}
+}
+
+func TestFairShare(t *testing.T) {
+	got := fairShare([]int{10, 500, 500}, 600)
+	want := []int{10, 295, 295}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("fairShare = %v, want %v", got, want)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package autocommit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/user/gitgud/internal/config"
+)
+
+// cacheTTL is how long a generated message stays in the cache
+const cacheTTL = 7 * 24 * time.Hour
+
+// messageCacheKey hashes everything that influences a generated message
+func messageCacheKey(gen generator, kind, diff, rules, branch, customContext string) string {
+	hash := sha256.New()
+	for _, part := range []string{kind, gen.llm.Name(), gen.model(), rules, branch, customContext, diff} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// cachedGenerate returns the cached message for key when there is one, and
+// otherwise calls generate and caches its result
+func cachedGenerate(gen generator, key string, generate func() (string, error)) (string, error) {
+	if !gen.noCache {
+		if message, ok := loadCachedMessage(key); ok {
+			fmt.Println("Using cached commit message (press r to regenerate or pass --no-cache to skip the cache)")
+			return message, nil
+		}
+	}
+
+	message, err := generate()
+	if err != nil {
+		return "", err
+	}
+
+	rememberMessage(gen, key, message)
+	return message, nil
+}
+
+// rememberMessage caches message under key, replacing any earlier message.
+// Retries call it directly so the latest message is the one returned next time.
+func rememberMessage(gen generator, key, message string) {
+	if gen.noCache {
+		return
+	}
+	if err := storeCachedMessage(key, message); err != nil {
+		fmt.Printf("Warning: Could not cache commit message: %v\n", err)
+	}
+}
+
+func loadCachedMessage(key string) (string, bool) {
+	cacheDir, err := config.CacheDir()
+	if err != nil {
+		return "", false
+	}
+
+	cachePath := filepath.Join(cacheDir, key+".txt")
+	info, err := os.Stat(cachePath)
+	if err != nil || time.Since(info.ModTime()) > cacheTTL {
+		return "", false
+	}
+
+	content, err := os.ReadFile(cachePath)
+	if err != nil || strings.TrimSpace(string(content)) == "" {
+		return "", false
+	}
+
+	return string(content), true
+}
+
+func storeCachedMessage(key, message string) error {
+	cacheDir, err := config.CacheDir()
+	if err != nil {
+		return err
+	}
+
+	pruneMessageCache(cacheDir)
+
+	return os.WriteFile(filepath.Join(cacheDir, key+".txt"), []byte(message), 0600)
+}
+
+// pruneMessageCache removes cached messages older than the cache TTL
+func pruneMessageCache(cacheDir string) {
+	entries, err := os.ReadDir(cacheDir)
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".txt") {
+			continue
+		}
+		info, err := entry.Info()
+		if err == nil && time.Since(info.ModTime()) > cacheTTL {
+			os.Remove(filepath.Join(cacheDir, entry.Name()))
+		}
+	}
+}
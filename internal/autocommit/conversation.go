@@ -0,0 +1,234 @@
+package autocommit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/user/gitgud/internal/provider"
+)
+
+// maxRuleRetries is how often the model is asked to fix a message that
+// breaks the rules before the message is handed to the user as it is
+const maxRuleRetries = 2
+
+// conversation is the exchange with the model about one set of changes.
+// Retries continue it, so the model refines its earlier answers with the
+// user's feedback instead of starting from scratch.
+type conversation struct {
+	cacheKey    string
+	buildPrompt func(ctx context.Context) (string, error)
+	prompt      string
+	// policy is what generated messages are checked against, nil to not check them
+	policy *lintPolicy
+	// finish is applied to every answer of the model
+	finish messageFinisher
+	// turns holds the answers and feedback that followed the prompt
+	turns []provider.Message
+}
+
+// start resets the conversation for a new prompt. The prompt is only built
+// when the provider is actually asked, so a cached message costs nothing.
+func (c *conversation) start(cacheKey string, policy *lintPolicy, finish messageFinisher, buildPrompt func(ctx context.Context) (string, error)) {
+	// A scope set from the changed paths is always allowed
+	if policy != nil {
+		allowed := policy.allowingScope(finish.scope)
+		policy = &allowed
+	}
+
+	c.cacheKey = cacheKey
+	c.policy = policy
+	c.finish = finish
+	c.buildPrompt = buildPrompt
+	c.prompt = ""
+	c.turns = nil
+}
+
+// generate returns the first message, reusing a cached one when possible
+func (c *conversation) generate(ctx context.Context, gen generator) (string, error) {
+	asked := false
+	message, err := cachedGenerate(gen, c.cacheKey, func() (string, error) {
+		asked = true
+		message, err := c.ask(ctx, gen)
+		if err != nil {
+			return "", err
+		}
+		return c.enforce(ctx, gen, message)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// A cached answer still becomes part of the conversation
+	if !asked {
+		message = c.finish.apply(message)
+		c.turns = append(c.turns, provider.Message{Role: provider.RoleAssistant, Content: message})
+	}
+	return message, nil
+}
+
+// candidates asks for n messages in parallel and returns the distinct ones.
+// Each request is told which variant it is, so the answers differ in
+// focus and wording rather than only by sampling noise. Call choose with the
+// message the user picks to continue the conversation from it.
+func (c *conversation) candidates(ctx context.Context, gen generator, n int) ([]string, error) {
+	if c.prompt == "" {
+		prompt, err := c.buildPrompt(ctx)
+		if err != nil {
+			return nil, err
+		}
+		c.prompt = prompt
+	}
+
+	messages := make([]string, n)
+	errs := make([]error, n)
+	sem := make(chan struct{}, maxParallelSummaries)
+	var wg sync.WaitGroup
+
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			variant := fmt.Sprintf(
+				"%s\n\nThis is option %d of %d offered to the user, so make it distinct: "+
+					"vary the emphasis, scope or wording compared to the other options.",
+				c.prompt, i+1, n,
+			)
+			messages[i], errs[i] = gen.quiet().complete(ctx, variant)
+		}(i)
+	}
+	wg.Wait()
+
+	seen := make(map[string]bool)
+	var distinct []string
+	for i, message := range messages {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		message = c.finish.apply(message)
+		if message == "" || seen[message] {
+			continue
+		}
+		seen[message] = true
+		distinct = append(distinct, message)
+	}
+	return distinct, nil
+}
+
+// choose records message as the model's answer, so later retries refine it.
+// The user picked it, so rule violations are only reported.
+func (c *conversation) choose(gen generator, message string) {
+	c.turns = append(c.turns, provider.Message{Role: provider.RoleAssistant, Content: message})
+	rememberMessage(gen, c.cacheKey, message)
+	if problems := c.problems(message); len(problems) > 0 {
+		fmt.Println("\nWarning: The chosen message breaks the commit message rules:")
+		printRuleProblems(problems)
+	}
+}
+
+// refine sends the user's feedback on the previous answer and returns the
+// revised message. An empty instruction asks for a different message.
+func (c *conversation) refine(ctx context.Context, gen generator, instruction string) (string, error) {
+	feedback := "Generate a different commit message for the same changes."
+	if instruction != "" {
+		feedback = fmt.Sprintf("Revise the commit message according to this feedback:\n%s", instruction)
+	}
+	feedback += "\n\nReply with ONLY the commit message, nothing else."
+
+	c.turns = append(c.turns, provider.Message{Role: provider.RoleUser, Content: feedback})
+	message, err := c.ask(ctx, gen)
+	if err != nil {
+		// Drop the feedback so a later retry does not send it twice
+		c.turns = c.turns[:len(c.turns)-1]
+		return "", err
+	}
+
+	message, err = c.enforce(ctx, gen, message)
+	if err != nil {
+		return "", err
+	}
+
+	rememberMessage(gen, c.cacheKey, message)
+	return message, nil
+}
+
+// enforce checks message against the policy and asks the model to fix the
+// problems, up to maxRuleRetries times. A message that still breaks the
+// rules is returned with a report, so the user can edit it or retry.
+func (c *conversation) enforce(ctx context.Context, gen generator, message string) (string, error) {
+	problems := c.problems(message)
+	for attempt := 1; len(problems) > 0 && attempt <= maxRuleRetries; attempt++ {
+		fmt.Printf("\nThe message breaks %d commit message rule(s), asking for a fix (attempt %d of %d)...\n", len(problems), attempt, maxRuleRetries)
+
+		var feedback strings.Builder
+		feedback.WriteString("The commit message breaks these rules:\n")
+		for _, problem := range problems {
+			feedback.WriteString(fmt.Sprintf("- line %d: %s\n", problem.Line, problem.Message))
+		}
+		feedback.WriteString("\nFix every problem while keeping the message accurate. Reply with ONLY the commit message, nothing else.")
+
+		c.turns = append(c.turns, provider.Message{Role: provider.RoleUser, Content: feedback.String()})
+		fixed, err := c.ask(ctx, gen)
+		if err != nil {
+			c.turns = c.turns[:len(c.turns)-1]
+			return "", err
+		}
+		message = fixed
+		problems = c.problems(message)
+	}
+
+	if len(problems) > 0 {
+		fmt.Printf("\nWarning: The model still breaks the commit message rules after %d retries:\n", maxRuleRetries)
+		printRuleProblems(problems)
+		fmt.Println("Edit the message or retry with feedback before committing it.")
+	}
+	return message, nil
+}
+
+// problems returns the rules message breaks, none when there is no policy
+func (c *conversation) problems(message string) []lintError {
+	if c.policy == nil {
+		return nil
+	}
+	return lintMessage(message, *c.policy)
+}
+
+// printRuleProblems lists rule violations of a generated message
+func printRuleProblems(problems []lintError) {
+	for _, problem := range problems {
+		fmt.Printf("  line %d, column %d: %s\n", problem.Line, problem.Column, problem.Message)
+	}
+}
+
+// ask sends the prompt and all turns so far and records the answer
+func (c *conversation) ask(ctx context.Context, gen generator) (string, error) {
+	if c.prompt == "" {
+		prompt, err := c.buildPrompt(ctx)
+		if err != nil {
+			return "", err
+		}
+		c.prompt = prompt
+	}
+
+	messages := append([]provider.Message{{Role: provider.RoleUser, Content: c.prompt}}, c.turns...)
+	message, err := gen.chat(ctx, messages)
+	if err != nil {
+		return "", err
+	}
+	message = c.finish.apply(message)
+
+	c.turns = append(c.turns, provider.Message{Role: provider.RoleAssistant, Content: message})
+	return message, nil
+}
+
+// parseReply splits a reply to the confirmation prompt into its lowercased
+// command and any feedback after it, e.g. "r make it shorter"
+func parseReply(line string) (string, string) {
+	line = strings.TrimSpace(line)
+	command, instruction, _ := strings.Cut(line, " ")
+	return strings.ToLower(command), strings.TrimSpace(instruction)
+}
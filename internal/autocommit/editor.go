@@ -0,0 +1,73 @@
+package autocommit
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/user/gitgud/internal/git"
+)
+
+// editCommitMessage opens message in the user's git editor, with a comment
+// header showing the diffstat of the changes being committed, and returns the
+// edited message without comment lines
+func editCommitMessage(message string, diffStat func() (string, error)) (string, error) {
+	file, err := os.CreateTemp("", "gg-COMMIT_EDITMSG-*.txt")
+	if err != nil {
+		return "", fmt.Errorf("error creating temp file: %v", err)
+	}
+	defer os.Remove(file.Name())
+
+	// Write the message followed by a git-style comment header
+	var content strings.Builder
+	content.WriteString(message)
+	content.WriteString("\n\n")
+	content.WriteString("# Please edit the commit message for your changes. Lines starting\n")
+	content.WriteString("# with '#' will be ignored, and an empty message aborts the commit.\n")
+	if stat, err := diffStat(); err != nil {
+		fmt.Printf("Warning: Could not get diffstat: %v\n", err)
+	} else if stat != "" {
+		content.WriteString("#\n# Changes to be committed:\n")
+		for _, line := range strings.Split(strings.TrimRight(stat, "\n"), "\n") {
+			content.WriteString("#" + line + "\n")
+		}
+	}
+
+	if _, err := file.WriteString(content.String()); err != nil {
+		file.Close()
+		return "", fmt.Errorf("error writing temp file: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		return "", fmt.Errorf("error writing temp file: %v", err)
+	}
+
+	// Run the editor through the shell, as git does, so editors with arguments work
+	editor := git.GetEditor()
+	cmd := exec.Command("sh", "-c", editor+` "$@"`, editor, file.Name())
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("error running editor %s: %v", editor, err)
+	}
+
+	edited, err := os.ReadFile(file.Name())
+	if err != nil {
+		return "", fmt.Errorf("error reading edited message: %v", err)
+	}
+
+	// Drop comment lines, as git commit --cleanup=strip does
+	var lines []string
+	for _, line := range strings.Split(string(edited), "\n") {
+		if !strings.HasPrefix(line, "#") {
+			lines = append(lines, strings.TrimRight(line, " \t\r"))
+		}
+	}
+	result := strings.TrimSpace(strings.Join(lines, "\n"))
+	if result == "" {
+		return "", fmt.Errorf("empty commit message")
+	}
+
+	return result, nil
+}
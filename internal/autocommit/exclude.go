@@ -0,0 +1,54 @@
+package autocommit
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/user/gitgud/internal/git"
+	"github.com/user/gitgud/internal/ignore"
+)
+
+// ignoreFileName lists paths, in gitignore syntax, whose content is never sent to the model
+const ignoreFileName = ".ggignore"
+
+// loadPromptIgnore reads .ggignore from the repository root. Problems are
+// reported and give a matcher that excludes nothing.
+func loadPromptIgnore() *ignore.Matcher {
+	root, err := git.GetRepoRoot()
+	if err != nil {
+		return nil
+	}
+
+	matcher, err := ignore.Load(filepath.Join(root, ignoreFileName))
+	if err != nil {
+		fmt.Printf("Warning: Could not load %s: %v\n", ignoreFileName, err)
+		return nil
+	}
+	return matcher
+}
+
+// excludeIgnoredFiles removes the files matched by matcher and describes
+// each of them with a single "changed: path (+N/-M)" line instead
+func excludeIgnoredFiles(files []fileDiff, matcher *ignore.Matcher) ([]fileDiff, string) {
+	if matcher.Empty() {
+		return files, ""
+	}
+
+	var kept []fileDiff
+	var excluded strings.Builder
+	for _, file := range files {
+		if !matcher.Match(file.Path) {
+			kept = append(kept, file)
+			continue
+		}
+
+		lines := classifyDiffLines(file.Diff)
+		excluded.WriteString(fmt.Sprintf("changed: %s (+%d/-%d)\n", file.Path, countChanges(lines, nil, true), countChanges(lines, nil, false)))
+	}
+
+	if excluded.Len() == 0 {
+		return files, ""
+	}
+	return kept, fmt.Sprintf("\nFiles whose content is left out (%s):\n%s\n", ignoreFileName, excluded.String())
+}
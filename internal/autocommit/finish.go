@@ -0,0 +1,242 @@
+package autocommit
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/user/gitgud/internal/config"
+	"github.com/user/gitgud/internal/ignore"
+)
+
+// messageFinisher puts what gg knows for certain into every generated
+// message, instead of relying on the model to get it right
+type messageFinisher struct {
+	// scope replaces the scope the model chose, empty to keep it
+	scope string
+	// subjectPrefix starts the description in the subject, e.g. "PROJ-123"
+	subjectPrefix string
+	// footers are added to the footer block, e.g. "Refs: PROJ-123" or trailers
+	footers []string
+}
+
+// newMessageFinisher prepares the finishing steps for a commit touching
+// paths, given relative to the repository root, on branch. trailers follow
+// the ticket references in the footer block.
+func newMessageFinisher(paths []string, branch string, trailers []string) (messageFinisher, error) {
+	cfg := config.LoadConfig()
+	if err := cfg.Ticket.Validate(); err != nil {
+		return messageFinisher{}, err
+	}
+	finish := messageFinisher{
+		scope: scopeForPaths(cfg.ScopeMap, cfg.MultiScope, paths),
+	}
+
+	// Reference the tickets named in the branch name
+	var references []string
+	for _, id := range ticketIDs(cfg.Ticket.Pattern, branch) {
+		references = append(references, strings.ReplaceAll(cfg.Ticket.Format, "{id}", id))
+	}
+	if cfg.Ticket.Placement == config.TicketPlacementSubject {
+		finish.subjectPrefix = strings.Join(references, " ")
+	} else {
+		finish.footers = append(finish.footers, references...)
+	}
+	finish.footers = append(finish.footers, trailers...)
+
+	return finish, nil
+}
+
+// apply finishes message. Applying it twice changes nothing, so cached and
+// already finished messages can safely go through it again.
+func (f messageFinisher) apply(message string) string {
+	if f.scope != "" {
+		message = forceScope(message, f.scope)
+	}
+	if f.subjectPrefix != "" {
+		message = prefixDescription(message, f.subjectPrefix)
+	}
+	if len(f.footers) > 0 {
+		message = addFooters(message, f.footers)
+	}
+	return message
+}
+
+// guidance tells the model what apply will enforce, so its answer fits
+func (f messageFinisher) guidance() string {
+	var guidance strings.Builder
+	if f.scope != "" {
+		guidance.WriteString(fmt.Sprintf("\n\nUse exactly %q as the scope of the commit message, it is derived from the changed paths.", f.scope))
+	}
+	if f.subjectPrefix != "" {
+		guidance.WriteString(fmt.Sprintf("\n\n%q is added to the start of the description automatically, do not write it yourself. "+
+			"It counts toward the subject line length, so keep the rest of the subject %d characters shorter.",
+			f.subjectPrefix, utf8.RuneCountInString(f.subjectPrefix)+1))
+	}
+	if len(f.footers) > 0 {
+		guidance.WriteString(fmt.Sprintf("\n\nThese footers are added automatically, do not write them yourself: %s", strings.Join(f.footers, ", ")))
+	}
+	return guidance.String()
+}
+
+// report prints the finishing steps that apply to the commit
+func (f messageFinisher) report() {
+	if f.scope != "" {
+		fmt.Printf("Using scope %q for the changed paths (scope_map)\n", f.scope)
+	}
+	if f.subjectPrefix != "" {
+		fmt.Printf("Adding %q from the branch name to the subject\n", f.subjectPrefix)
+	}
+	for _, footer := range f.footers {
+		fmt.Printf("Adding footer %q\n", footer)
+	}
+}
+
+// ticketIDs returns the distinct ticket IDs pattern finds in branch: the
+// first group of each match, or the whole match when pattern has no groups.
+// An invalid pattern is reported and finds nothing.
+func ticketIDs(pattern, branch string) []string {
+	if pattern == "" || branch == "" || branch == "unknown" {
+		return nil
+	}
+
+	regex, err := regexp.Compile(pattern)
+	if err != nil {
+		fmt.Printf("Warning: Invalid ticket pattern %q: %v\n", pattern, err)
+		return nil
+	}
+
+	var ids []string
+	for _, match := range regex.FindAllStringSubmatch(branch, -1) {
+		id := match[0]
+		if len(match) > 1 {
+			id = match[1]
+		}
+		if id != "" {
+			ids = appendUnique(ids, id)
+		}
+	}
+	return ids
+}
+
+// scopeForPaths maps every path to the scope of the first mapping matching
+// it. Paths no mapping matches are left out. It returns the single scope the
+// paths share, multiScope when they span several, or "" when none matched.
+func scopeForPaths(mappings []config.ScopeMapping, multiScope string, paths []string) string {
+	if len(mappings) == 0 {
+		return ""
+	}
+
+	matchers := make([]*ignore.Matcher, len(mappings))
+	for i, mapping := range mappings {
+		matchers[i] = ignore.Parse(mapping.Path)
+	}
+
+	var scopes []string
+	for _, p := range paths {
+		for i, matcher := range matchers {
+			if matcher.Match(p) {
+				scopes = appendUnique(scopes, mappings[i].Scope)
+				break
+			}
+		}
+	}
+
+	switch len(scopes) {
+	case 0:
+		return ""
+	case 1:
+		return scopes[0]
+	default:
+		return multiScope
+	}
+}
+
+// forceScope sets the scope of a Conventional Commit header, replacing the
+// one the model chose. Messages without such a header are left alone.
+func forceScope(message, scope string) string {
+	subject, rest, hasRest := strings.Cut(message, "\n")
+	colon := strings.Index(subject, ":")
+	if colon == -1 {
+		return message
+	}
+
+	match := headerPrefixPattern.FindStringSubmatch(subject[:colon])
+	if match == nil {
+		return message
+	}
+
+	subject = match[1] + "(" + scope + ")" + match[3] + subject[colon:]
+	if hasRest {
+		return subject + "\n" + rest
+	}
+	return subject
+}
+
+// prefixDescription puts prefix at the start of the description of a
+// Conventional Commit subject, or of the whole subject otherwise. A subject
+// that already contains prefix is left alone.
+func prefixDescription(message, prefix string) string {
+	subject, rest, hasRest := strings.Cut(message, "\n")
+	if strings.Contains(subject, prefix) {
+		return message
+	}
+
+	if colon := strings.Index(subject, ":"); colon != -1 && headerPrefixPattern.MatchString(subject[:colon]) {
+		subject = subject[:colon+1] + " " + prefix + " " + strings.TrimLeft(subject[colon+1:], " ")
+	} else {
+		subject = prefix + " " + subject
+	}
+
+	if hasRest {
+		return subject + "\n" + rest
+	}
+	return subject
+}
+
+// addFooters appends footers to the footer block of message, starting one
+// when the last paragraph is not a footer block. Footers the message already
+// contains are skipped.
+func addFooters(message string, footers []string) string {
+	message = strings.TrimRight(message, " \t\n")
+	lines := strings.Split(message, "\n")
+
+	var missing []string
+	for _, footer := range footers {
+		if !containsLine(lines, footer) && !containsLine(missing, footer) {
+			missing = append(missing, footer)
+		}
+	}
+	if len(missing) == 0 {
+		return message
+	}
+
+	// The last paragraph is a footer block when every line of it is a footer
+	start := len(lines)
+	for start > 1 && strings.TrimSpace(lines[start-1]) != "" {
+		start--
+	}
+	inFooters := start > 1
+	for _, line := range lines[start:] {
+		if !footerPattern.MatchString(line) {
+			inFooters = false
+			break
+		}
+	}
+
+	if !inFooters {
+		message += "\n"
+	}
+	return message + "\n" + strings.Join(missing, "\n")
+}
+
+// containsLine reports whether lines contain line, ignoring case and surrounding space
+func containsLine(lines []string, line string) bool {
+	for _, l := range lines {
+		if strings.EqualFold(strings.TrimSpace(l), strings.TrimSpace(line)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,149 @@
+package autocommit
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTicketIDs(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		branch  string
+		want    []string
+	}{
+		{name: "group", pattern: `([A-Z]+-\d+)`, branch: "feature/PROJ-123-login", want: []string{"PROJ-123"}},
+		{name: "group inside a match", pattern: `issue-(\d+)`, branch: "issue-42-fix-crash", want: []string{"42"}},
+		{name: "whole match", pattern: `[A-Z]+-\d+`, branch: "feature/PROJ-123-login", want: []string{"PROJ-123"}},
+		{name: "several IDs", pattern: `[A-Z]+-\d+`, branch: "PROJ-1-and-OPS-2", want: []string{"PROJ-1", "OPS-2"}},
+		{name: "duplicate IDs", pattern: `[A-Z]+-\d+`, branch: "PROJ-1-PROJ-2-PROJ-1", want: []string{"PROJ-1", "PROJ-2"}},
+		{name: "empty group", pattern: `PROJ-(\d*)`, branch: "PROJ-x"},
+		{name: "no match", pattern: `[A-Z]+-\d+`, branch: "main"},
+		{name: "invalid pattern", pattern: `([A-Z]+`, branch: "PROJ-1"},
+		{name: "no pattern", branch: "PROJ-1"},
+		{name: "unknown branch", pattern: `.+`, branch: "unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ticketIDs(tt.pattern, tt.branch); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ticketIDs(%q, %q) = %v, want %v", tt.pattern, tt.branch, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrefixDescription(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    string
+	}{
+		{name: "conventional commit", message: "feat(auth): add login", want: "feat(auth): PROJ-1 add login"},
+		{name: "breaking", message: "feat!: drop v1", want: "feat!: PROJ-1 drop v1"},
+		{name: "body kept", message: "fix: handle nil\n\nThe map was not initialized.", want: "fix: PROJ-1 handle nil\n\nThe map was not initialized."},
+		{name: "extra spaces after the colon", message: "fix:   handle nil", want: "fix: PROJ-1 handle nil"},
+		{name: "already at the start", message: "feat: PROJ-1 add login", want: "feat: PROJ-1 add login"},
+		{name: "already elsewhere in the subject", message: "feat: add login (PROJ-1)", want: "feat: add login (PROJ-1)"},
+		{name: "only in the body", message: "feat: add login\n\nPROJ-1", want: "feat: PROJ-1 add login\n\nPROJ-1"},
+		{name: "not a conventional commit", message: "Add login", want: "PROJ-1 Add login"},
+		{name: "colon in a plain subject", message: "Update docs: fix typo", want: "PROJ-1 Update docs: fix typo"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := prefixDescription(tt.message, "PROJ-1")
+			if got != tt.want {
+				t.Errorf("prefixDescription(%q) = %q, want %q", tt.message, got, tt.want)
+			}
+			if again := prefixDescription(got, "PROJ-1"); again != got {
+				t.Errorf("prefixing twice gave %q", again)
+			}
+		})
+	}
+}
+
+func TestAddFooters(t *testing.T) {
+	coAuthor := "Co-authored-by: Jane Doe <jane@example.com>"
+	signOff := "Signed-off-by: John Roe <john@example.com>"
+
+	tests := []struct {
+		name    string
+		message string
+		footers []string
+		want    string
+	}{
+		{
+			name:    "subject only",
+			message: "feat: add login",
+			footers: []string{coAuthor},
+			want:    "feat: add login\n\n" + coAuthor,
+		},
+		{
+			name:    "subject that looks like a footer",
+			message: "fix: handle nil\n",
+			footers: []string{signOff},
+			want:    "fix: handle nil\n\n" + signOff,
+		},
+		{
+			name:    "body that is not a footer block",
+			message: "feat: add login\n\nUsers asked for a remember me box.\n\n",
+			footers: []string{coAuthor, signOff},
+			want:    "feat: add login\n\nUsers asked for a remember me box.\n\n" + coAuthor + "\n" + signOff,
+		},
+		{
+			name:    "paragraph mixing footers and text",
+			message: "feat: add login\n\nRefs: PROJ-1\nand some more text",
+			footers: []string{signOff},
+			want:    "feat: add login\n\nRefs: PROJ-1\nand some more text\n\n" + signOff,
+		},
+		{
+			name:    "existing footer block",
+			message: "feat: add login\n\nUsers asked for it.\n\nRefs: PROJ-1\nReviewed-by: Sam <sam@example.com>",
+			footers: []string{coAuthor},
+			want:    "feat: add login\n\nUsers asked for it.\n\nRefs: PROJ-1\nReviewed-by: Sam <sam@example.com>\n" + coAuthor,
+		},
+		{
+			name:    "breaking change footer",
+			message: "feat!: drop v1\n\nBREAKING CHANGE: v1 clients must upgrade",
+			footers: []string{"Refs: PROJ-1", signOff},
+			want:    "feat!: drop v1\n\nBREAKING CHANGE: v1 clients must upgrade\nRefs: PROJ-1\n" + signOff,
+		},
+		{
+			name:    "issue footer",
+			message: "fix: handle nil\n\nCloses #42",
+			footers: []string{signOff},
+			want:    "fix: handle nil\n\nCloses #42\n" + signOff,
+		},
+		{
+			name:    "footer the model already wrote in another case",
+			message: "feat: add login\n\nco-authored-by: jane doe <JANE@example.com>",
+			footers: []string{"Co-authored-by: Jane Doe <jane@example.com>", signOff},
+			want:    "feat: add login\n\nco-authored-by: jane doe <JANE@example.com>\n" + signOff,
+		},
+		{
+			name:    "duplicate footers",
+			message: "feat: add login",
+			footers: []string{"Refs: PROJ-1", "refs: proj-1"},
+			want:    "feat: add login\n\nRefs: PROJ-1",
+		},
+		{
+			name:    "every footer present",
+			message: "feat: add login\n\n" + signOff + "\n",
+			footers: []string{signOff},
+			want:    "feat: add login\n\n" + signOff,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := addFooters(tt.message, tt.footers)
+			if got != tt.want {
+				t.Errorf("addFooters =\n%q\nwant\n%q", got, tt.want)
+			}
+			if again := addFooters(got, tt.footers); again != got {
+				t.Errorf("adding the footers twice gave %q", again)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package autocommit
+
+import (
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// frontMatterDelimiter opens and closes the front matter block of a rules file
+const frontMatterDelimiter = "---"
+
+// splitFrontMatter separates a leading "---" delimited front matter block
+// from the rest of content. ok is false when there is no front matter.
+func splitFrontMatter(content string) (frontMatter string, body string, ok bool) {
+	content = strings.TrimPrefix(content, "\ufeff")
+	normalized := strings.ReplaceAll(content, "\r\n", "\n")
+	if !strings.HasPrefix(normalized, frontMatterDelimiter+"\n") {
+		return "", content, false
+	}
+
+	rest := normalized[len(frontMatterDelimiter)+1:]
+	lines := strings.SplitAfter(rest, "\n")
+	offset := 0
+	for _, line := range lines {
+		if strings.TrimRight(line, "\n") == frontMatterDelimiter {
+			return rest[:offset], strings.TrimLeft(rest[offset+len(line):], "\n"), true
+		}
+		offset += len(line)
+	}
+
+	// An unterminated block is not front matter
+	return "", content, false
+}
+
+// parseFrontMatter parses the YAML front matter of a rules file into its
+// top-level keys. Line numbers in errors count from the opening delimiter.
+func parseFrontMatter(frontMatter string) (map[string]any, error) {
+	values := make(map[string]any)
+	// The leading newline stands in for the opening delimiter
+	if err := yaml.Unmarshal([]byte("\n"+frontMatter), &values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
@@ -0,0 +1,117 @@
+package autocommit
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRulesFrontMatter(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		settings RuleSettings
+		rules    string
+	}{
+		{
+			name:    "no front matter",
+			content: "Use Conventional Commits.\n",
+			rules:   "Use Conventional Commits.\n",
+		},
+		{
+			name:     "inline and block lists",
+			content:  "---\ntypes: [feat, fix]\nscopes:\n  - api\n  - ui\n---\nBody\n",
+			settings: RuleSettings{Types: []string{"feat", "fix"}, Scopes: []string{"api", "ui"}, FromFrontMatter: true},
+			rules:    "Body\n",
+		},
+		{
+			name:     "single value list",
+			content:  "---\nfooters: Refs\n---\nBody\n",
+			settings: RuleSettings{Footers: []string{"Refs"}, FromFrontMatter: true},
+			rules:    "Body\n",
+		},
+		{
+			name:     "quoting and comments",
+			content:  "---\n# Checked on every commit\nlanguage: \"English # US\" # not German\nscopes: ['a,b', \"c\"]\nmax_subject_length: 50 # characters\nrequire_body: true\n---\nBody\n",
+			settings: RuleSettings{Language: "English # US", Scopes: []string{"a,b", "c"}, MaxSubjectLength: 50, BodyRequired: true, FromFrontMatter: true},
+			rules:    "Body\n",
+		},
+		{
+			name:     "numeric scopes",
+			content:  "---\nscopes: [v1, 2024]\n---\nBody\n",
+			settings: RuleSettings{Scopes: []string{"v1", "2024"}, FromFrontMatter: true},
+			rules:    "Body\n",
+		},
+		{
+			name:     "byte order mark and CRLF",
+			content:  "\ufeff---\r\nmax_body_line_length: 72\r\n---\r\nBody\r\n",
+			settings: RuleSettings{MaxBodyLineLength: 72, FromFrontMatter: true},
+			rules:    "Body\n",
+		},
+		{
+			name:    "unterminated front matter",
+			content: "---\ntypes: [feat]\nBody\n",
+			rules:   "---\ntypes: [feat]\nBody\n",
+		},
+		{
+			name:    "unknown key keeps the body",
+			content: "---\ntpyes: [feat]\n---\nBody\n",
+			rules:   "Body\n",
+		},
+		{
+			name:    "invalid YAML keeps the body",
+			content: "---\ntypes: [feat\n---\nBody\n",
+			rules:   "Body\n",
+		},
+		{
+			name:    "duplicate key keeps the body",
+			content: "---\ntypes: [feat]\ntypes: [fix]\n---\nBody\n",
+			rules:   "Body\n",
+		},
+		{
+			name:    "malformed value keeps the body",
+			content: "---\nmax_subject_length: -5\nrequire_body: maybe\n---\nBody\n",
+			rules:   "Body\n",
+		},
+		{
+			name:    "nested list keeps the body",
+			content: "---\ntypes:\n  - [feat]\n---\nBody\n",
+			rules:   "Body\n",
+		},
+		{
+			name:    "merge outside the user file keeps the body",
+			content: "---\nmerge: override\n---\nBody\n",
+			rules:   "Body\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := parseRules(tt.content, "project", ".autocommit.md")
+			if !reflect.DeepEqual(rules.Settings, tt.settings) {
+				t.Errorf("settings = %+v, want %+v", rules.Settings, tt.settings)
+			}
+			if rules.Rules != tt.rules {
+				t.Errorf("rules = %q, want %q", rules.Rules, tt.rules)
+			}
+		})
+	}
+}
+
+func TestParseRulesUserMerge(t *testing.T) {
+	rules := parseRules("---\nmerge: override\n---\nWrap at 72.\n", "user", "autocommit.md")
+	if rules.Settings.Merge != mergeOverride {
+		t.Errorf("merge = %q, want %q", rules.Settings.Merge, mergeOverride)
+	}
+}
+
+func TestParseFrontMatterLineNumbers(t *testing.T) {
+	// Line 1 of the file is the opening delimiter
+	_, err := parseFrontMatter("types: [feat]\nscopes: [api]\ntypes: [fix]\n")
+	if err == nil {
+		t.Fatal("expected an error for a duplicate key")
+	}
+	want := "yaml: unmarshal errors:\n  line 4: mapping key \"types\" already defined at line 2"
+	if err.Error() != want {
+		t.Errorf("error = %q, want %q", err, want)
+	}
+}
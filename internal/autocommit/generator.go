@@ -0,0 +1,161 @@
+package autocommit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+
+	"github.com/user/gitgud/internal/config"
+	"github.com/user/gitgud/internal/provider"
+)
+
+// Options holds the per-invocation settings given on the command line
+type Options struct {
+	// Generation overrides the configured model, token and temperature settings
+	Generation config.GenerationSettings
+	// Offline builds the message from the diff locally without any provider
+	Offline bool
+	// NoCache neither reads nor writes the generated message cache
+	NoCache bool
+	// NoStream waits for the full message instead of printing it as it is generated
+	NoStream bool
+	// Candidates is the number of messages to generate and choose from
+	Candidates int
+	// Context is the custom context given on the command line, nil to ask for it
+	Context *string
+	// Yes commits the generated message without asking for confirmation
+	Yes bool
+	// DryRun prints the generated message without committing
+	DryRun bool
+	// MessageOnly writes only the generated message to stdout, for piping
+	MessageOnly bool
+	// Staged uses and commits only the staged changes, nil for the config default
+	Staged *bool
+	// Amend regenerates the message of HEAD, including newly staged changes
+	Amend bool
+	// Force amends HEAD even when it was already pushed
+	Force bool
+	// CoAuthors are added as Co-authored-by trailers, each as "Name <email>"
+	CoAuthors []string
+	// PickCoAuthors asks which recent authors to add as co-authors
+	PickCoAuthors bool
+	// SignOff adds a Signed-off-by trailer, nil for the config default
+	SignOff *bool
+}
+
+// interactive reports whether the user is asked for input on stdin
+func (o Options) interactive() bool {
+	return !o.Yes && !o.DryRun && !o.MessageOnly
+}
+
+var (
+	// errGenerationCanceled is returned when the user interrupts generation with Ctrl-C
+	errGenerationCanceled = errors.New("generation canceled")
+	// errCandidateSelectionExited is returned when the user leaves the candidate list without choosing
+	errCandidateSelectionExited = errors.New("no candidate chosen")
+	// errCoAuthorSelectionExited is returned when the user leaves the co-author picker with Exit
+	errCoAuthorSelectionExited = errors.New("co-author selection exited")
+	// errSecretsBlocked is returned when a diff with secrets must not be sent to the provider
+	errSecretsBlocked = errors.New("secrets found in the changes")
+)
+
+// generator bundles the configured LLM provider with the generation
+// settings resolved for the running command
+type generator struct {
+	llm      provider.Provider
+	settings config.GenerationSettings
+	// summaries remembers chunk summaries of very large diffs across retries
+	summaries *sync.Map
+	// noCache disables the message cache
+	noCache bool
+	// stream prints the response token by token while it is generated
+	stream bool
+}
+
+// newGenerator creates the configured LLM provider for command. Missing API
+// keys are only asked for in interactive runs.
+func newGenerator(command string, opts Options) (generator, error) {
+	cfg := config.LoadConfig()
+
+	llm, err := provider.New(cfg, opts.interactive())
+	if err != nil {
+		return generator{}, err
+	}
+
+	return generator{
+		llm:       llm,
+		settings:  cfg.GenerationFor(command, opts.Generation),
+		summaries: &sync.Map{},
+		noCache:   opts.NoCache,
+		stream:    !opts.NoStream,
+	}, nil
+}
+
+// model returns the model name that requests will be sent with
+func (g generator) model() string {
+	if g.settings.Model != "" {
+		return g.settings.Model
+	}
+	return g.llm.DefaultModel()
+}
+
+// quiet returns a copy of the generator that does not stream its output,
+// for requests whose response is not shown to the user
+func (g generator) quiet() generator {
+	g.stream = false
+	return g
+}
+
+// complete sends a single user prompt and returns the trimmed response
+func (g generator) complete(ctx context.Context, prompt string) (string, error) {
+	return g.chat(ctx, []provider.Message{
+		{
+			Role:    provider.RoleUser,
+			Content: prompt,
+		},
+	})
+}
+
+// chat sends a conversation and returns the trimmed response.
+// When streaming, the response is printed as it arrives.
+func (g generator) chat(ctx context.Context, messages []provider.Message) (string, error) {
+	req := provider.Request{
+		Model:       g.model(),
+		Messages:    messages,
+		MaxTokens:   g.settings.MaxTokens,
+		Temperature: g.settings.Temperature,
+	}
+
+	var response string
+	var err error
+	if g.stream {
+		response, err = g.llm.Stream(ctx, req, func(token string) {
+			fmt.Print(token)
+		})
+		fmt.Println()
+	} else {
+		response, err = g.llm.Complete(ctx, req)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(response), nil
+}
+
+// withInterrupt runs generate with a context that is canceled when the user
+// presses Ctrl-C, so an in-flight request is aborted instead of killing gg
+func withInterrupt(generate func(ctx context.Context) (string, error)) (string, error) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	message, err := generate(ctx)
+	if ctx.Err() != nil {
+		return "", errGenerationCanceled
+	}
+	return message, err
+}
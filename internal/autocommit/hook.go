@@ -0,0 +1,95 @@
+package autocommit
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/user/gitgud/internal/config"
+	"github.com/user/gitgud/internal/git"
+)
+
+// HandlePrepareCommitMsg runs as git's prepare-commit-msg hook. It fills
+// messageFile with a message generated from the staged changes, using the
+// same rules and branch context as gg ac. source is the second hook argument.
+// Failures are reported but never abort the commit.
+func HandlePrepareCommitMsg(opts Options, messageFile, source string) {
+	// Skip merges, squashes, amends (-c/-C/--amend) and messages given with -m or -F
+	if source != "" && source != "template" {
+		return
+	}
+
+	// Git shows hook output in the terminal, keep it off stdout
+	os.Stdout = os.Stderr
+
+	if !git.HasStagedChanges() {
+		return
+	}
+
+	diff, err := git.GetStagedDiff()
+	if err != nil {
+		fmt.Printf("gg: Could not get staged diff: %v\n", err)
+		return
+	}
+
+	// Keep secrets from leaving the machine, nobody can be asked from a hook
+	screened, err := screenSecrets([]string{diff}, nil)
+	if err != nil {
+		fmt.Println("gg: Not generating a commit message, the changes contain secrets.")
+		return
+	}
+	diff = screened[0]
+
+	// Nobody can answer a prompt from a hook, a missing API key is reported instead
+	opts.NoStream = true
+	opts.Yes = true
+	gen, err := newGenerator(config.CommandAutocommit, opts)
+	if err != nil {
+		fmt.Printf("gg: Not generating a commit message: %v\n", err)
+		return
+	}
+
+	// Rules, scope, ticket references and trailers follow the staged paths and
+	// the config, nobody can pick co-authors here
+	paths, err := git.GetStagedPaths("")
+	if err != nil {
+		fmt.Printf("gg: Could not get staged paths: %v\n", err)
+	}
+	rules, err := getAutocommitRules(paths)
+	if err != nil {
+		fmt.Printf("gg: Could not load autocommit rules: %v\n", err)
+		rules = builtinRules()
+	}
+	branchName, err := git.GetCurrentBranch()
+	if err != nil {
+		branchName = "unknown"
+	}
+	trailers, err := commitTrailers(opts, gen)
+	if err != nil {
+		fmt.Printf("gg: Not adding trailers: %v\n", err)
+	}
+	finish, err := newMessageFinisher(paths, branchName, trailers)
+	if err != nil {
+		fmt.Printf("gg: Not generating a commit message: %v\n", err)
+		return
+	}
+
+	fmt.Printf("gg: Generating commit message with %s (%s)...\n", gen.model(), gen.llm.Name())
+	commitMsg, err := withInterrupt(func(ctx context.Context) (string, error) {
+		return generateCommitMessage(ctx, gen, &conversation{}, commitScope{staged: true}, diff, "", rules, finish)
+	})
+	if err != nil {
+		fmt.Printf("gg: Could not generate commit message: %v\n", err)
+		return
+	}
+
+	// Put the message above the comments (and template) git already wrote
+	existing, err := os.ReadFile(messageFile)
+	if err != nil {
+		fmt.Printf("gg: Could not read %s: %v\n", messageFile, err)
+		return
+	}
+	if err := os.WriteFile(messageFile, []byte(commitMsg+"\n"+string(existing)), 0644); err != nil {
+		fmt.Printf("gg: Could not write %s: %v\n", messageFile, err)
+	}
+}
@@ -0,0 +1,368 @@
+package autocommit
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/user/gitgud/internal/config"
+	"github.com/user/gitgud/internal/git"
+)
+
+// defaultCommitTypes are the Conventional Commit types allowed when the rules do not list any
+var defaultCommitTypes = []string{"feat", "fix", "docs", "style", "refactor", "perf", "test", "build", "ci", "chore", "revert"}
+
+var (
+	// headerPrefixPattern matches the part of a header before the colon: type, optional scope and breaking marker
+	headerPrefixPattern = regexp.MustCompile(`^([A-Za-z]+)(?:\(([^()]*)\))?(!)?$`)
+	// ruleItemPattern matches a list item that names a type or scope, e.g. "- feat: A new feature" or "- `api`"
+	ruleItemPattern = regexp.MustCompile("^[-*+]\\s+`?([a-z][a-z0-9_./-]*)`?(?:\\s*[:(-]|\\s*$)")
+	// subjectLengthPattern finds a subject length limit such as "Keep the subject under 50 characters"
+	subjectLengthPattern = regexp.MustCompile(`(?i)(subject|header|first line|summary)\D*(\d+)\s*(char|characters)\b`)
+	// generatedSubjectPattern matches subjects git writes itself, which are not linted
+	generatedSubjectPattern = regexp.MustCompile(`^(Merge |Revert "|fixup! |squash! |amend! )`)
+	// footerPattern matches a footer line such as "Refs: PROJ-1", "Closes #42" or "BREAKING CHANGE: ..."
+	footerPattern = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9-]*|BREAKING CHANGE)(?:: | #)\S`)
+)
+
+// lintPolicy is what a commit message is checked against
+type lintPolicy struct {
+	Types             []string
+	Scopes            []string
+	MaxSubjectLength  int
+	MaxBodyLineLength int
+	BodyRequired      bool
+	Footers           []string
+}
+
+// lintError is a single problem found in a commit message, with its 1-based position
+type lintError struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+// HandleLintMessage checks the commit message in file against the project's
+// autocommit rules and exits with status 1 when it breaks them. It is also
+// what the commit-msg hook runs.
+func HandleLintMessage(file string) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		fmt.Printf("Error reading commit message: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Get autocommit rules for the staged paths, which are what is being committed
+	paths, err := git.GetStagedPaths("")
+	if err != nil {
+		fmt.Printf("Warning: Could not get staged paths: %v\n", err)
+	}
+	rules, err := getAutocommitRules(paths)
+	if err != nil {
+		fmt.Printf("Warning: Could not load autocommit rules: %v\n", err)
+		rules = builtinRules()
+	}
+
+	// The scope gg ac sets from the changed paths is always allowed
+	cfg := config.LoadConfig()
+	policy := policyFromRules(rules).allowingScope(scopeForPaths(cfg.ScopeMap, cfg.MultiScope, paths))
+
+	problems := lintMessage(string(content), policy)
+	if len(problems) == 0 {
+		fmt.Printf("Commit message follows the %s rules from %s\n", rules.Source, rules.files())
+		return
+	}
+
+	for _, problem := range problems {
+		fmt.Printf("%s:%d:%d: %s\n", file, problem.Line, problem.Column, problem.Message)
+	}
+	fmt.Printf("\nCommit message does not follow the %s rules from %s\n", rules.Source, rules.files())
+	fmt.Println("Edit the message and commit again, or pass --no-verify to git commit to skip the check.")
+	os.Exit(1)
+}
+
+// policyFromRules builds the policy from the front matter settings. Whatever
+// they leave out is read from the rules text: types and scopes are list items
+// following a line that introduces them, such as "Common types include:" or
+// "## Scopes", and the subject length limit is taken from a sentence like
+// "Keep the subject under 50 characters".
+func policyFromRules(rules AutocommitRules) lintPolicy {
+	policy := policyFromText(rules.Rules)
+
+	settings := rules.Settings
+	if len(settings.Types) > 0 {
+		policy.Types = settings.Types
+	}
+	if len(settings.Scopes) > 0 {
+		policy.Scopes = settings.Scopes
+	}
+	if settings.MaxSubjectLength > 0 {
+		policy.MaxSubjectLength = settings.MaxSubjectLength
+	}
+	policy.MaxBodyLineLength = settings.MaxBodyLineLength
+	policy.BodyRequired = settings.BodyRequired
+	policy.Footers = settings.Footers
+	return policy
+}
+
+// allowingScope returns the policy with scope added to the allowed scopes.
+// A policy that allows any scope, or an empty scope, leaves it unchanged.
+func (p lintPolicy) allowingScope(scope string) lintPolicy {
+	if scope != "" && len(p.Scopes) > 0 {
+		p.Scopes = appendUnique(append([]string(nil), p.Scopes...), scope)
+	}
+	return p
+}
+
+// generationPolicy returns the policy generated messages are checked
+// against, or nil when no rules file has front matter. Free-text rules only
+// guide the model, and need not follow Conventional Commits at all.
+func generationPolicy(rules AutocommitRules) *lintPolicy {
+	if !rules.Settings.FromFrontMatter {
+		return nil
+	}
+	policy := policyFromRules(rules)
+	return &policy
+}
+
+// policyFromText reads the allowed types, allowed scopes and subject length
+// limit from free-text rules
+func policyFromText(rules string) lintPolicy {
+	policy := lintPolicy{MaxSubjectLength: maxSubjectLength}
+	section := ""
+
+	for _, line := range strings.Split(rules, "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		if match := subjectLengthPattern.FindStringSubmatch(trimmed); match != nil {
+			if limit, err := strconv.Atoi(match[2]); err == nil && limit > 0 {
+				policy.MaxSubjectLength = limit
+			}
+		}
+
+		switch {
+		case trimmed == "":
+			// Blank lines may separate a list from its introduction
+		case ruleItemPattern.MatchString(trimmed):
+			name := ruleItemPattern.FindStringSubmatch(trimmed)[1]
+			if section == "types" {
+				policy.Types = appendUnique(policy.Types, name)
+			} else if section == "scopes" {
+				policy.Scopes = appendUnique(policy.Scopes, name)
+			}
+		case strings.HasPrefix(trimmed, "#") || strings.HasSuffix(trimmed, ":"):
+			// A heading or a line ending in a colon introduces the next list
+			lower := strings.ToLower(trimmed)
+			section = ""
+			if strings.Contains(lower, "scope") {
+				section = "scopes"
+			} else if strings.Contains(lower, "type") {
+				section = "types"
+			}
+		default:
+			section = ""
+		}
+	}
+
+	if len(policy.Types) == 0 {
+		policy.Types = defaultCommitTypes
+	}
+	return policy
+}
+
+// lintMessage checks message against policy. Comment lines and everything
+// below git's scissors line are ignored, as git commit strips them.
+func lintMessage(message string, policy lintPolicy) []lintError {
+	// Collect the lines that end up in the commit with their line numbers
+	var lines []string
+	var numbers []int
+	for i, line := range strings.Split(message, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if strings.HasPrefix(line, "# ------------------------ >8 ------------------------") {
+			break
+		}
+		if strings.HasPrefix(line, "#") || (len(lines) == 0 && line == "") {
+			continue
+		}
+		lines = append(lines, line)
+		numbers = append(numbers, i+1)
+	}
+
+	if len(lines) == 0 {
+		return []lintError{{Line: 1, Column: 1, Message: "commit message is empty"}}
+	}
+
+	subject := lines[0]
+	if generatedSubjectPattern.MatchString(subject) {
+		return nil
+	}
+
+	problems := lintSubject(subject, policy)
+	for i := range problems {
+		problems[i].Line = numbers[0]
+	}
+
+	if len(lines) > 1 && lines[1] != "" {
+		problems = append(problems, lintError{
+			Line:    numbers[1],
+			Column:  1,
+			Message: "the body must be separated from the subject by a blank line",
+		})
+	}
+
+	problems = append(problems, lintBody(lines, numbers, policy)...)
+	return problems
+}
+
+// lintBody checks body line lengths and the required body and footers.
+// Footers are the lines of the last paragraph when every one of them looks
+// like a footer, they are not checked for length.
+func lintBody(lines []string, numbers []int, policy lintPolicy) []lintError {
+	var problems []lintError
+
+	// Trailing blank lines are stripped by git
+	for len(lines) > 1 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+		numbers = numbers[:len(numbers)-1]
+	}
+
+	// Find the last paragraph after the subject
+	start := len(lines)
+	for start > 1 && lines[start-1] != "" {
+		start--
+	}
+	footers := make(map[string]bool)
+	bodyEnd := len(lines)
+	if start > 1 && start < len(lines) {
+		isFooter := true
+		for _, line := range lines[start:] {
+			if !footerPattern.MatchString(line) {
+				isFooter = false
+				break
+			}
+		}
+		if isFooter {
+			for _, line := range lines[start:] {
+				token := footerPattern.FindStringSubmatch(line)[1]
+				footers[strings.ToLower(token)] = true
+			}
+			bodyEnd = start
+		}
+	}
+
+	if policy.BodyRequired {
+		hasBody := false
+		for _, line := range lines[1:bodyEnd] {
+			if strings.TrimSpace(line) != "" {
+				hasBody = true
+				break
+			}
+		}
+		if !hasBody {
+			problems = append(problems, lintError{Line: numbers[0] + 1, Column: 1, Message: "a body explaining the change is required"})
+		}
+	}
+
+	if policy.MaxBodyLineLength > 0 {
+		for i, line := range lines[1:bodyEnd] {
+			// A single long word such as a URL cannot be wrapped
+			if utf8.RuneCountInString(line) <= policy.MaxBodyLineLength || !strings.Contains(line, " ") || strings.Contains(line, "://") {
+				continue
+			}
+			problems = append(problems, lintError{
+				Line:    numbers[i+1],
+				Column:  policy.MaxBodyLineLength + 1,
+				Message: fmt.Sprintf("body line is %d characters long, wrap it at %d", utf8.RuneCountInString(line), policy.MaxBodyLineLength),
+			})
+		}
+	}
+
+	for _, token := range policy.Footers {
+		if !footers[strings.ToLower(token)] {
+			problems = append(problems, lintError{
+				Line:    numbers[len(numbers)-1],
+				Column:  1,
+				Message: fmt.Sprintf("footer %q is required, e.g. \"%s: ...\" in the last paragraph", token, token),
+			})
+		}
+	}
+
+	return problems
+}
+
+// lintSubject checks the first line of a commit message. Line numbers are
+// left for the caller to fill in.
+func lintSubject(subject string, policy lintPolicy) []lintError {
+	var problems []lintError
+
+	colon := strings.Index(subject, ":")
+	if colon == -1 {
+		return []lintError{{Column: 1, Message: "subject must have the form <type>[(scope)][!]: <description>"}}
+	}
+
+	match := headerPrefixPattern.FindStringSubmatch(subject[:colon])
+	if match == nil {
+		return []lintError{{Column: 1, Message: fmt.Sprintf("%q is not a valid <type>[(scope)][!] prefix", subject[:colon])}}
+	}
+
+	commitType, scope := match[1], match[2]
+	if !contains(policy.Types, commitType) {
+		problems = append(problems, lintError{
+			Column:  1,
+			Message: fmt.Sprintf("type %q is not allowed, use one of: %s", commitType, strings.Join(policy.Types, ", ")),
+		})
+	}
+
+	if strings.Contains(subject[:colon], "(") {
+		scopeColumn := len(commitType) + 2
+		if strings.TrimSpace(scope) == "" {
+			problems = append(problems, lintError{Column: scopeColumn, Message: "scope must not be empty"})
+		} else if len(policy.Scopes) > 0 {
+			for _, part := range strings.Split(scope, ",") {
+				part = strings.TrimSpace(part)
+				if !contains(policy.Scopes, part) {
+					problems = append(problems, lintError{
+						Column:  scopeColumn,
+						Message: fmt.Sprintf("scope %q is not allowed, use one of: %s", part, strings.Join(policy.Scopes, ", ")),
+					})
+				}
+			}
+		}
+	}
+
+	description := subject[colon+1:]
+	if !strings.HasPrefix(description, " ") {
+		problems = append(problems, lintError{Column: colon + 2, Message: "a single space must follow the colon"})
+	}
+	if strings.TrimSpace(description) == "" {
+		problems = append(problems, lintError{Column: colon + 2, Message: "description must not be empty"})
+	}
+
+	if length := utf8.RuneCountInString(subject); length > policy.MaxSubjectLength {
+		problems = append(problems, lintError{
+			Column:  policy.MaxSubjectLength + 1,
+			Message: fmt.Sprintf("subject is %d characters long, the limit is %d", length, policy.MaxSubjectLength),
+		})
+	}
+
+	return problems
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func appendUnique(values []string, value string) []string {
+	if contains(values, value) {
+		return values
+	}
+	return append(values, value)
+}
@@ -0,0 +1,242 @@
+package autocommit
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLintPolicyAllowingScope(t *testing.T) {
+	tests := []struct {
+		name   string
+		scopes []string
+		scope  string
+		want   []string
+	}{
+		{name: "mapped scope added", scopes: []string{"git", "cli"}, scope: "multi", want: []string{"git", "cli", "multi"}},
+		{name: "already allowed", scopes: []string{"git", "cli"}, scope: "git", want: []string{"git", "cli"}},
+		{name: "any scope allowed", scope: "multi"},
+		{name: "no mapped scope", scopes: []string{"git"}, want: []string{"git"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := policyFromText("")
+			policy.Scopes = tt.scopes
+			got := policy.allowingScope(tt.scope)
+			if !reflect.DeepEqual(got.Scopes, tt.want) {
+				t.Errorf("scopes = %v, want %v", got.Scopes, tt.want)
+			}
+			if len(tt.scopes) > 0 && len(policy.Scopes) != len(tt.scopes) {
+				t.Errorf("the original policy was changed: %v", policy.Scopes)
+			}
+			if problems := lintMessage("feat("+tt.scope+"): add it\n", got); tt.scope != "" && len(problems) > 0 {
+				t.Errorf("mapped scope rejected: %+v", problems)
+			}
+		})
+	}
+}
+
+func TestLintMessage(t *testing.T) {
+	// base is the policy of rules that set nothing
+	base := policyFromText("")
+	with := func(change func(*lintPolicy)) lintPolicy {
+		policy := base
+		change(&policy)
+		return policy
+	}
+
+	tests := []struct {
+		name    string
+		message string
+		policy  lintPolicy
+		want    []lintError
+	}{
+		{name: "valid", message: "feat(api): add login\n", policy: base},
+		{name: "breaking", message: "feat(api)!: drop the v1 endpoints\n", policy: base},
+		{name: "breaking without scope", message: "refactor!: drop Go 1.20\n", policy: base},
+		{name: "comments and leading blank lines", message: "\n# Please enter the commit message\nfix: handle nil\n", policy: base},
+		{
+			name:    "no type",
+			message: "Add login\n",
+			policy:  base,
+			want:    []lintError{{Line: 1, Column: 1, Message: "subject must have the form <type>[(scope)][!]: <description>"}},
+		},
+		{
+			name:    "invalid prefix",
+			message: "feat api: add login\n",
+			policy:  base,
+			want:    []lintError{{Line: 1, Column: 1, Message: `"feat api" is not a valid <type>[(scope)][!] prefix`}},
+		},
+		{
+			name:    "unknown type",
+			message: "feature: add login\n",
+			policy:  with(func(p *lintPolicy) { p.Types = []string{"feat", "fix"} }),
+			want:    []lintError{{Line: 1, Column: 1, Message: `type "feature" is not allowed, use one of: feat, fix`}},
+		},
+		{
+			name:    "no space after colon",
+			message: "feat:add login\n",
+			policy:  base,
+			want:    []lintError{{Line: 1, Column: 6, Message: "a single space must follow the colon"}},
+		},
+		{
+			name:    "empty description",
+			message: "feat(api): \n",
+			policy:  base,
+			want: []lintError{
+				{Line: 1, Column: 11, Message: "a single space must follow the colon"},
+				{Line: 1, Column: 11, Message: "description must not be empty"},
+			},
+		},
+		{
+			name:    "empty scope",
+			message: "feat(): add login\n",
+			policy:  base,
+			want:    []lintError{{Line: 1, Column: 6, Message: "scope must not be empty"}},
+		},
+		{
+			name:    "allowed scope list",
+			message: "feat(api, ui): add login\n",
+			policy:  with(func(p *lintPolicy) { p.Scopes = []string{"api", "ui"} }),
+		},
+		{
+			name:    "scope list with a disallowed scope",
+			message: "feat(api,db): add login\n",
+			policy:  with(func(p *lintPolicy) { p.Scopes = []string{"api", "ui"} }),
+			want:    []lintError{{Line: 1, Column: 6, Message: `scope "db" is not allowed, use one of: api, ui`}},
+		},
+		{
+			name:    "any scope without a list",
+			message: "feat(anything): add login\n",
+			policy:  base,
+		},
+		{
+			name:    "subject too long",
+			message: "feat: add a login form with remember me\n",
+			policy:  with(func(p *lintPolicy) { p.MaxSubjectLength = 20 }),
+			want:    []lintError{{Line: 1, Column: 21, Message: "subject is 39 characters long, the limit is 20"}},
+		},
+		{
+			name:    "subject length counts characters",
+			message: "feat: ajouter la fenêtre\n",
+			policy:  with(func(p *lintPolicy) { p.MaxSubjectLength = 24 }),
+		},
+		{
+			name:    "no blank line after subject",
+			message: "feat: add login\nWith a remember me box.\n",
+			policy:  base,
+			want:    []lintError{{Line: 2, Column: 1, Message: "the body must be separated from the subject by a blank line"}},
+		},
+		{
+			name:    "required body missing",
+			message: "feat: add login\n\nRefs: PROJ-1\n",
+			policy:  with(func(p *lintPolicy) { p.BodyRequired = true }),
+			want:    []lintError{{Line: 2, Column: 1, Message: "a body explaining the change is required"}},
+		},
+		{
+			name:    "required body present",
+			message: "feat: add login\n\nUsers asked for it.\n\nRefs: PROJ-1\n",
+			policy:  with(func(p *lintPolicy) { p.BodyRequired = true }),
+		},
+		{
+			name:    "required footer present in any case",
+			message: "feat: add login\n\nUsers asked for it.\n\nrefs: PROJ-1\n",
+			policy:  with(func(p *lintPolicy) { p.Footers = []string{"Refs"} }),
+		},
+		{
+			name:    "required footer missing",
+			message: "feat: add login\n\nUsers asked for it.\n",
+			policy:  with(func(p *lintPolicy) { p.Footers = []string{"Refs"} }),
+			want:    []lintError{{Line: 3, Column: 1, Message: `footer "Refs" is required, e.g. "Refs: ..." in the last paragraph`}},
+		},
+		{
+			name:    "required footer only in the body",
+			message: "feat: add login\n\nRefs: PROJ-1\nwas asked for by users.\n",
+			policy:  with(func(p *lintPolicy) { p.Footers = []string{"Refs"} }),
+			want:    []lintError{{Line: 4, Column: 1, Message: `footer "Refs" is required, e.g. "Refs: ..." in the last paragraph`}},
+		},
+		{
+			name:    "breaking change and issue footers",
+			message: "feat!: drop v1\n\nThe v1 endpoints were deprecated a year ago.\n\nBREAKING CHANGE: v1 clients must upgrade\nRefs #12\n",
+			policy:  with(func(p *lintPolicy) { p.Footers = []string{"Refs"} }),
+		},
+		{
+			name:    "long body line",
+			message: "feat: add login\n\nThis line is far too long for the limit.\n",
+			policy:  with(func(p *lintPolicy) { p.MaxBodyLineLength = 20 }),
+			want:    []lintError{{Line: 3, Column: 21, Message: "body line is 40 characters long, wrap it at 20"}},
+		},
+		{
+			name:    "long URL and footer lines",
+			message: "feat: add login\n\nSee https://example.com/a/very/long/path/to/the/design\n\nReviewed-by: Someone With A Long Name <someone@example.com>\n",
+			policy:  with(func(p *lintPolicy) { p.MaxBodyLineLength = 20 }),
+		},
+		{
+			name:    "scissors line",
+			message: "feat: add login\n# ------------------------ >8 ------------------------\nDo not modify or remove the line above.\ndiff --git a/x b/x\n",
+			policy:  base,
+		},
+		{name: "merge", message: "Merge branch 'feature' into main\n", policy: base},
+		{name: "revert", message: "Revert \"feat: add login\"\n\nThis reverts commit abc.\n", policy: base},
+		{name: "fixup", message: "fixup! feat: add login\n", policy: base},
+		{name: "squash", message: "squash! feat: add login\n", policy: base},
+		{
+			name:    "empty",
+			message: "\n# Please enter the commit message\n",
+			policy:  base,
+			want:    []lintError{{Line: 1, Column: 1, Message: "commit message is empty"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := lintMessage(tt.message, tt.policy)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("lintMessage(%q) =\n%+v\nwant\n%+v", tt.message, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicyFromText(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules string
+		want  lintPolicy
+	}{
+		{
+			name:  "nothing set",
+			rules: "Write clear messages.\n",
+			want:  lintPolicy{Types: defaultCommitTypes, MaxSubjectLength: maxSubjectLength},
+		},
+		{
+			name: "types, scopes and length",
+			rules: "# Rules\n\nCommon types include:\n- feat: A new feature\n- fix: A bug fix\n\n## Scopes\n" +
+				"- `api`\n- ui (the frontend)\n\nKeep the subject under 50 characters.\n",
+			want: lintPolicy{Types: []string{"feat", "fix"}, Scopes: []string{"api", "ui"}, MaxSubjectLength: 50},
+		},
+		{
+			name:  "first line limit",
+			rules: "The first line must not exceed 60 characters.\n",
+			want:  lintPolicy{Types: defaultCommitTypes, MaxSubjectLength: 60},
+		},
+		{
+			name:  "lists under other headings",
+			rules: "## Examples\n- feat: add login\n- docs: fix typo\n",
+			want:  lintPolicy{Types: defaultCommitTypes, MaxSubjectLength: maxSubjectLength},
+		},
+		{
+			name:  "text ends a list",
+			rules: "Types:\n- feat\nOther changes use:\n- chore\n",
+			want:  lintPolicy{Types: []string{"feat"}, MaxSubjectLength: maxSubjectLength},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policyFromText(tt.rules); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("policyFromText =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
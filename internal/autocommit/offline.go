@@ -0,0 +1,288 @@
+package autocommit
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// maxSubjectLength is the longest subject the offline generator will produce
+const maxSubjectLength = 72
+
+// Change kinds detected in a diff
+const (
+	changeAdded    = "added"
+	changeModified = "modified"
+	changeDeleted  = "deleted"
+	changeRenamed  = "renamed"
+)
+
+// fileChange summarizes the changes made to a single file in a diff
+type fileChange struct {
+	Path      string
+	Kind      string
+	Additions int
+	Deletions int
+}
+
+// generateOfflineCommitMessage builds a Conventional Commit message from the
+// shape of the diff alone, without calling any provider. The result only
+// depends on the diff, so the same changes always produce the same message.
+func generateOfflineCommitMessage(diff string) (string, error) {
+	changes := parseDiffChanges(diff)
+	if len(changes) == 0 {
+		return "", fmt.Errorf("no file changes found in diff")
+	}
+
+	commitType := inferCommitType(changes)
+	scope := inferScope(changes, commitType)
+
+	header := commitType
+	if scope != "" {
+		header += "(" + scope + ")"
+	}
+	header += ": "
+
+	subject := header + describeChanges(changes, true)
+	if len(subject) > maxSubjectLength {
+		subject = header + describeChanges(changes, false)
+	}
+
+	var body strings.Builder
+	for i, change := range changes {
+		if i == 20 {
+			body.WriteString(fmt.Sprintf("- ...and %d more file(s)\n", len(changes)-i))
+			break
+		}
+		body.WriteString(fmt.Sprintf("- %s %s (+%d/-%d)\n", change.Kind, change.Path, change.Additions, change.Deletions))
+	}
+
+	return subject + "\n\n" + strings.TrimRight(body.String(), "\n"), nil
+}
+
+// parseDiffChanges extracts per-file change information from unified diff
+// output, including the "New file:" sections that GetGitDiff and
+// GetFileDiff append for files git does not track yet.
+func parseDiffChanges(diff string) []fileChange {
+	byPath := make(map[string]*fileChange)
+	var order []string
+	var current *fileChange
+	inHunk, inContent := false, false
+
+	track := func(filePath, kind string) *fileChange {
+		if change, ok := byPath[filePath]; ok {
+			return change
+		}
+		change := &fileChange{Path: filePath, Kind: kind}
+		byPath[filePath] = change
+		order = append(order, filePath)
+		return change
+	}
+
+	for _, line := range strings.Split(diff, "\n") {
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			inHunk, inContent = false, false
+			current = nil
+			if idx := strings.LastIndex(line, " b/"); idx != -1 {
+				current = track(line[idx+3:], changeModified)
+			}
+		case strings.HasPrefix(line, "New file: "):
+			inHunk, inContent = false, false
+			current = track(strings.TrimPrefix(line, "New file: "), changeAdded)
+			current.Kind = changeAdded
+		case current == nil:
+			continue
+		case line == "File content:":
+			inContent = true
+		case inContent:
+			// Every non-empty line of an untracked file is an addition
+			if line != "" {
+				current.Additions++
+			}
+		case strings.HasPrefix(line, "@@"):
+			inHunk = true
+		case !inHunk && strings.HasPrefix(line, "new file mode"):
+			current.Kind = changeAdded
+		case !inHunk && strings.HasPrefix(line, "deleted file mode"):
+			current.Kind = changeDeleted
+		case !inHunk && strings.HasPrefix(line, "rename from "):
+			current.Kind = changeRenamed
+		case !inHunk:
+			continue
+		case strings.HasPrefix(line, "+"):
+			current.Additions++
+		case strings.HasPrefix(line, "-"):
+			current.Deletions++
+		}
+	}
+
+	changes := make([]fileChange, 0, len(order))
+	for _, filePath := range order {
+		changes = append(changes, *byPath[filePath])
+	}
+	return changes
+}
+
+// inferCommitType picks a Conventional Commit type from the kinds of files
+// touched and the overall shape of the change
+func inferCommitType(changes []fileChange) string {
+	if allChanges(changes, isTestFile) {
+		return "test"
+	}
+	if allChanges(changes, isDocFile) {
+		return "docs"
+	}
+	if allChanges(changes, isDependencyFile) {
+		return "build"
+	}
+	if allChanges(changes, isCIFile) {
+		return "ci"
+	}
+
+	additions, deletions := 0, 0
+	allDeleted := true
+	for _, change := range changes {
+		additions += change.Additions
+		deletions += change.Deletions
+		if change.Kind != changeDeleted {
+			allDeleted = false
+		}
+		if change.Kind == changeAdded && !isTestFile(change.Path) && !isDocFile(change.Path) {
+			return "feat"
+		}
+	}
+
+	switch {
+	case allDeleted:
+		return "refactor"
+	case additions+deletions <= 20:
+		return "fix"
+	case deletions >= additions:
+		return "refactor"
+	default:
+		return "feat"
+	}
+}
+
+// inferScope derives a scope from the deepest directory shared by all changes
+func inferScope(changes []fileChange, commitType string) string {
+	if commitType == "build" {
+		return "deps"
+	}
+
+	common := path.Dir(changes[0].Path)
+	for _, change := range changes[1:] {
+		dir := path.Dir(change.Path)
+		for common != "." && common != dir && !strings.HasPrefix(dir, common+"/") {
+			common = path.Dir(common)
+		}
+	}
+
+	if common == "." || common == "/" {
+		return ""
+	}
+
+	// Generic container directories make poor scopes on their own
+	scope := path.Base(common)
+	switch scope {
+	case "internal", "pkg", "src", "lib", "app":
+		return ""
+	}
+	return scope
+}
+
+// describeChanges builds the subject description. With names enabled it
+// lists file names, otherwise it falls back to file counts.
+func describeChanges(changes []fileChange, names bool) string {
+	groups := map[string][]string{}
+	for _, change := range changes {
+		groups[change.Kind] = append(groups[change.Kind], path.Base(change.Path))
+	}
+
+	verbs := []struct{ kind, verb string }{
+		{changeAdded, "add"},
+		{changeModified, "update"},
+		{changeRenamed, "rename"},
+		{changeDeleted, "remove"},
+	}
+
+	var clauses []string
+	for _, v := range verbs {
+		files := groups[v.kind]
+		if len(files) == 0 {
+			continue
+		}
+		sort.Strings(files)
+		if names && len(files) <= 3 {
+			clauses = append(clauses, v.verb+" "+joinWithAnd(files))
+		} else if len(files) == 1 {
+			clauses = append(clauses, v.verb+" 1 file")
+		} else {
+			clauses = append(clauses, fmt.Sprintf("%s %d files", v.verb, len(files)))
+		}
+	}
+
+	return joinWithAnd(clauses)
+}
+
+// joinWithAnd joins items as "a", "a and b" or "a, b and c"
+func joinWithAnd(items []string) string {
+	if len(items) <= 1 {
+		return strings.Join(items, "")
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " and " + items[len(items)-1]
+}
+
+func allChanges(changes []fileChange, match func(string) bool) bool {
+	for _, change := range changes {
+		if !match(change.Path) {
+			return false
+		}
+	}
+	return true
+}
+
+func isTestFile(filePath string) bool {
+	base := path.Base(filePath)
+	return strings.HasSuffix(base, "_test.go") ||
+		strings.Contains(base, ".test.") ||
+		strings.Contains(base, ".spec.") ||
+		strings.HasPrefix(base, "test_") ||
+		strings.HasPrefix(filePath, "test/") ||
+		strings.HasPrefix(filePath, "tests/") ||
+		strings.Contains(filePath, "/test/") ||
+		strings.Contains(filePath, "/tests/") ||
+		strings.Contains(filePath, "testdata/")
+}
+
+func isDocFile(filePath string) bool {
+	ext := strings.ToLower(path.Ext(filePath))
+	base := strings.ToUpper(path.Base(filePath))
+	return ext == ".md" || ext == ".rst" || ext == ".adoc" ||
+		strings.HasPrefix(base, "README") ||
+		strings.HasPrefix(base, "CHANGELOG") ||
+		strings.HasPrefix(base, "LICENSE") ||
+		strings.HasPrefix(filePath, "docs/") ||
+		strings.Contains(filePath, "/docs/")
+}
+
+func isDependencyFile(filePath string) bool {
+	switch path.Base(filePath) {
+	case "go.mod", "go.sum",
+		"package.json", "package-lock.json", "yarn.lock", "pnpm-lock.yaml",
+		"Cargo.toml", "Cargo.lock",
+		"requirements.txt", "poetry.lock", "Pipfile", "Pipfile.lock", "pyproject.toml",
+		"Gemfile", "Gemfile.lock", "composer.json", "composer.lock":
+		return true
+	}
+	return strings.HasPrefix(filePath, "vendor/")
+}
+
+func isCIFile(filePath string) bool {
+	return strings.HasPrefix(filePath, ".github/workflows/") ||
+		strings.HasPrefix(filePath, ".circleci/") ||
+		path.Base(filePath) == ".gitlab-ci.yml" ||
+		path.Base(filePath) == "Jenkinsfile"
+}
@@ -0,0 +1,185 @@
+package autocommit
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseDiffChanges(t *testing.T) {
+	diff := strings.Join([]string{
+		"diff --git a/cmd/root.go b/cmd/root.go",
+		"index 1111111..2222222 100644",
+		"--- a/cmd/root.go",
+		"+++ b/cmd/root.go",
+		"@@ -1,3 +1,4 @@",
+		" package cmd",
+		"-var a = 1",
+		"+var a = 2",
+		"+var b = 3",
+		"@@ -10 +11 @@",
+		"-// old",
+		"+// new",
+		"diff --git a/lib/new.go b/lib/new.go",
+		"new file mode 100644",
+		"--- /dev/null",
+		"+++ b/lib/new.go",
+		"@@ -0,0 +1 @@",
+		"+package lib",
+		"diff --git a/lib/old.go b/lib/old.go",
+		"deleted file mode 100644",
+		"--- a/lib/old.go",
+		"+++ /dev/null",
+		"@@ -1,2 +0,0 @@",
+		"-package lib",
+		"-",
+		"diff --git a/a.txt b/b.txt",
+		"similarity index 100%",
+		"rename from a.txt",
+		"rename to b.txt",
+		"diff --git a/logo.png b/logo.png",
+		"new file mode 100644",
+		"Binary file: image/png, 71 bytes, 16x8",
+		// The unstaged part of a staged file is merged into it
+		"diff --git a/cmd/root.go b/cmd/root.go",
+		"@@ -1 +1 @@",
+		"+var c = 4",
+		"",
+		"New file: notes.txt",
+		"File content:",
+		"first",
+		"",
+		"second",
+		"",
+		"New file: empty.txt",
+		"Empty file",
+	}, "\n")
+
+	want := []fileChange{
+		{Path: "cmd/root.go", Kind: changeModified, Additions: 4, Deletions: 2},
+		{Path: "lib/new.go", Kind: changeAdded, Additions: 1},
+		{Path: "lib/old.go", Kind: changeDeleted, Deletions: 2},
+		{Path: "b.txt", Kind: changeRenamed},
+		{Path: "logo.png", Kind: changeAdded},
+		{Path: "notes.txt", Kind: changeAdded, Additions: 2},
+		{Path: "empty.txt", Kind: changeAdded},
+	}
+	if got := parseDiffChanges(diff); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseDiffChanges =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestInferCommitType(t *testing.T) {
+	modified := func(path string, additions, deletions int) fileChange {
+		return fileChange{Path: path, Kind: changeModified, Additions: additions, Deletions: deletions}
+	}
+
+	tests := []struct {
+		name    string
+		changes []fileChange
+		want    string
+	}{
+		{name: "tests only", changes: []fileChange{modified("lint_test.go", 50, 0), modified("testdata/a.txt", 1, 1)}, want: "test"},
+		{name: "docs only", changes: []fileChange{modified("README.md", 50, 0), modified("docs/guide.txt", 1, 1)}, want: "docs"},
+		{name: "dependencies only", changes: []fileChange{modified("go.mod", 1, 1), modified("go.sum", 4, 2)}, want: "build"},
+		{name: "CI only", changes: []fileChange{modified(".github/workflows/ci.yml", 3, 1)}, want: "ci"},
+		{name: "new source file", changes: []fileChange{modified("main.go", 1, 1), {Path: "lib/new.go", Kind: changeAdded, Additions: 2}}, want: "feat"},
+		{name: "new test file is not a feature", changes: []fileChange{modified("main.go", 1, 1), {Path: "main_test.go", Kind: changeAdded, Additions: 2}}, want: "fix"},
+		{name: "everything deleted", changes: []fileChange{{Path: "old.go", Kind: changeDeleted, Deletions: 100}}, want: "refactor"},
+		{name: "small change", changes: []fileChange{modified("main.go", 12, 8)}, want: "fix"},
+		{name: "large change removing more", changes: []fileChange{modified("main.go", 30, 40)}, want: "refactor"},
+		{name: "large change adding more", changes: []fileChange{modified("main.go", 40, 30)}, want: "feat"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := inferCommitType(tt.changes); got != tt.want {
+				t.Errorf("inferCommitType = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInferScope(t *testing.T) {
+	tests := []struct {
+		name       string
+		paths      []string
+		commitType string
+		want       string
+	}{
+		{name: "one directory", paths: []string{"internal/git/git.go", "internal/git/newfile.go"}, want: "git"},
+		{name: "nested directories", paths: []string{"internal/git/git.go", "internal/git/attr/attr.go"}, want: "git"},
+		{name: "generic container", paths: []string{"internal/git/git.go", "internal/ui/ui.go"}},
+		{name: "different top-level directories", paths: []string{"cmd/root.go", "web/app.js"}},
+		{name: "root files", paths: []string{"main.go", "cmd/root.go"}},
+		{name: "similar prefix is not a parent", paths: []string{"api/a.go", "apiclient/b.go"}},
+		{name: "dependencies", paths: []string{"go.mod"}, commitType: "build", want: "deps"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var changes []fileChange
+			for _, p := range tt.paths {
+				changes = append(changes, fileChange{Path: p, Kind: changeModified})
+			}
+			if got := inferScope(changes, tt.commitType); got != tt.want {
+				t.Errorf("inferScope(%v) = %q, want %q", tt.paths, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateOfflineCommitMessage(t *testing.T) {
+	diff := fileWithChange("internal/git/git.go", "a", "b").Diff + fileWithChange("internal/git/newfile.go", "c", "d").Diff
+
+	message, err := generateOfflineCommitMessage(diff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "fix(git): update git.go and newfile.go\n\n- modified internal/git/git.go (+1/-1)\n- modified internal/git/newfile.go (+1/-1)"
+	if message != want {
+		t.Errorf("message =\n%s\nwant\n%s", message, want)
+	}
+
+	// The same diff always gives the same message
+	for i := 0; i < 5; i++ {
+		if again, _ := generateOfflineCommitMessage(diff); again != message {
+			t.Fatalf("message changed between runs:\n%s", again)
+		}
+	}
+
+	if _, err := generateOfflineCommitMessage(""); err == nil {
+		t.Error("expected an error for an empty diff")
+	}
+}
+
+func TestGenerateOfflineCommitMessageLongSubject(t *testing.T) {
+	var diff strings.Builder
+	for _, name := range []string{"configuration_loader.go", "provider_registry.go", "conversation_state.go"} {
+		diff.WriteString(fileWithChange("server/"+name, "a", "b").Diff)
+	}
+	for i := 0; i < 25; i++ {
+		diff.WriteString(fmt.Sprintf("New file: server/new%02d.txt\nFile content:\nx\n", i))
+	}
+
+	message, err := generateOfflineCommitMessage(diff.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// File names do not fit, so the subject counts files instead
+	subject, body, _ := strings.Cut(message, "\n\n")
+	if subject != "feat(server): add 25 files and update 3 files" {
+		t.Errorf("subject = %q", subject)
+	}
+	if len(subject) > maxSubjectLength {
+		t.Errorf("subject is %d characters long, over %d", len(subject), maxSubjectLength)
+	}
+
+	// The body lists 20 files and counts the rest
+	lines := strings.Split(body, "\n")
+	if len(lines) != 21 || lines[20] != "- ...and 8 more file(s)" {
+		t.Errorf("body =\n%s", body)
+	}
+}
@@ -0,0 +1,466 @@
+package autocommit
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/user/gitgud/internal/config"
+	"github.com/user/gitgud/internal/git"
+)
+
+// rulesFileName is the rules file looked up at the repository root and in
+// the directories of changed paths
+const rulesFileName = ".autocommit.md"
+
+// How the user rules file combines with project rules, set by its merge setting
+const (
+	// mergeAppend adds the user rules below the project rules, which win conflicting settings
+	mergeAppend = "append"
+	// mergeOverride adds the user rules, which win conflicting settings
+	mergeOverride = "override"
+	// mergeDisable uses the user rules only in repositories without project
+	// rules, leaving projects that have their own untouched
+	mergeDisable = "disable"
+)
+
+// RuleSettings are the machine-readable settings from the front matter of
+// an .autocommit.md file. Generated messages are checked against them.
+type RuleSettings struct {
+	Types            []string
+	Scopes           []string
+	MaxSubjectLength int
+	BodyRequired     bool
+	// Footers are footer tokens every message must carry, e.g. "Refs"
+	Footers           []string
+	Language          string
+	MaxBodyLineLength int
+	// Merge is how the user rules file combines with project rules, see mergeAppend
+	Merge string
+	// FromFrontMatter is set when a rules file had front matter, which opts
+	// generated messages into being checked
+	FromFrontMatter bool
+}
+
+// getAutocommitRules resolves the rules for a commit touching paths, given
+// relative to the repository root. The base rules come from .autocommit.md at
+// the repository root, or next to the executable, or are built in. Rules
+// files in the directories of the changed paths are layered on top of them.
+func getAutocommitRules(paths []string) (AutocommitRules, error) {
+	// Rules are resolved from the repository root, so subdirectories find them too
+	root, err := git.GetRepoRoot()
+	if err != nil {
+		root, err = os.Getwd()
+		if err != nil {
+			return AutocommitRules{}, fmt.Errorf("error getting current directory: %v", err)
+		}
+	}
+
+	rules, err := getBaseRules(root)
+	if err != nil {
+		return AutocommitRules{}, err
+	}
+	return layerDirectoryRules(rules, root, paths), nil
+}
+
+// getBaseRules returns the rules every commit starts from: the project's
+// rules merged with the user's, or the user's alone, or the default rules
+func getBaseRules(root string) (AutocommitRules, error) {
+	user := getUserRules()
+
+	// First, check for the project's .autocommit.md at the repository root
+	projectRulesPath := filepath.Join(root, rulesFileName)
+	content, err := os.ReadFile(projectRulesPath)
+	if err == nil {
+		project := parseRules(string(content), "project", projectRulesPath)
+		if user.Path == "" || user.Settings.Merge == mergeDisable {
+			return project, nil
+		}
+		return mergeUserRules(project, user), nil
+	}
+
+	// Personal rules stand in for the default rules
+	if user.Path != "" {
+		return user, nil
+	}
+
+	// If not found there, check executable directory for default rules
+	exePath, err := os.Executable()
+	if err != nil {
+		return AutocommitRules{}, fmt.Errorf("error getting executable path: %v", err)
+	}
+
+	defaultRulesPath := filepath.Join(filepath.Dir(exePath), rulesFileName)
+	content, err = os.ReadFile(defaultRulesPath)
+	if err == nil {
+		return parseRules(string(content), "default", defaultRulesPath), nil
+	}
+
+	// Default rules if no .autocommit.md is found anywhere
+	return builtinRules(), nil
+}
+
+// builtinRules returns the rules used when no .autocommit.md applies or the
+// rules files cannot be read
+func builtinRules() AutocommitRules {
+	return AutocommitRules{
+		Rules:  "Please follow the Conventional Commits format: <type>(<scope>): <description>",
+		Source: "root",
+		Path:   "built-in",
+	}
+}
+
+// getUserRules reads ~/.gg/autocommit.md. A missing file gives rules with
+// an empty Path. A file that cannot be read is reported and skipped, so it
+// does not keep the project rules from being used.
+func getUserRules() AutocommitRules {
+	userRulesPath, err := config.UserRulesPath()
+	if err != nil {
+		return AutocommitRules{}
+	}
+
+	content, err := os.ReadFile(userRulesPath)
+	if os.IsNotExist(err) {
+		return AutocommitRules{}
+	}
+	if err != nil {
+		fmt.Printf("Warning: Ignoring personal rules, error reading %s: %v\n", userRulesPath, err)
+		return AutocommitRules{}
+	}
+
+	user := parseRules(string(content), "user", userRulesPath)
+	if user.Settings.Merge == "" {
+		user.Settings.Merge = mergeAppend
+	}
+	return user
+}
+
+// mergeUserRules adds the user's rules to the project's. The user's text
+// follows the project's, and the merge mode decides whose settings win.
+func mergeUserRules(project, user AutocommitRules) AutocommitRules {
+	merged := project
+	merged.User = user.Path
+	merged.UserMerge = user.Settings.Merge
+
+	precedence := "the project rules above take precedence"
+	merged.Settings = overrideSettings(user.Settings, project.Settings)
+	if user.Settings.Merge == mergeOverride {
+		precedence = "these take precedence over the project rules above"
+		merged.Settings = overrideSettings(project.Settings, user.Settings)
+	}
+	merged.Settings.Merge = ""
+
+	merged.Rules = fmt.Sprintf("%s\n\nPersonal rules of the committer (%s):\n%s", strings.TrimSpace(project.Rules), precedence, strings.TrimSpace(user.Rules))
+	return merged
+}
+
+// layerDirectoryRules adds the rules files found in the directories of paths,
+// and those directories' parents, to base. Their text follows the base rules,
+// parents first. Their settings override the inherited ones for the paths
+// below them, and are then combined across all paths.
+func layerDirectoryRules(base AutocommitRules, root string, paths []string) AutocommitRules {
+	layers := make(map[string]AutocommitRules)
+	var dirs []string
+	for _, p := range paths {
+		for _, dir := range parentDirs(p) {
+			if _, seen := layers[dir]; seen {
+				continue
+			}
+
+			rulesPath := filepath.Join(root, filepath.FromSlash(dir), rulesFileName)
+			content, err := os.ReadFile(rulesPath)
+			if err != nil {
+				// Remember the directory has no rules so it is only checked once
+				layers[dir] = AutocommitRules{}
+				continue
+			}
+			layer := parseRules(string(content), "directory", path.Join(dir, rulesFileName))
+			layers[dir] = layer
+			dirs = append(dirs, dir)
+		}
+	}
+
+	if len(dirs) == 0 {
+		return base
+	}
+
+	// Parents come before their subdirectories
+	sort.Slice(dirs, func(i, j int) bool {
+		di, dj := strings.Count(dirs[i], "/"), strings.Count(dirs[j], "/")
+		if di != dj {
+			return di < dj
+		}
+		return dirs[i] < dirs[j]
+	})
+
+	merged := base
+	var text strings.Builder
+	text.WriteString(strings.TrimSpace(base.Rules))
+	for _, dir := range dirs {
+		layer := layers[dir]
+		merged.Layers = append(merged.Layers, layer.Path)
+		text.WriteString(fmt.Sprintf("\n\nAdditional rules for changes under %s/:\n%s", dir, strings.TrimSpace(layer.Rules)))
+	}
+	merged.Rules = text.String()
+
+	// Every path is governed by the base settings and the layers above it
+	perPath := make([]RuleSettings, 0, len(paths))
+	for _, p := range paths {
+		settings := base.Settings
+		for _, dir := range parentDirs(p) {
+			settings = overrideSettings(settings, layers[dir].Settings)
+		}
+		perPath = append(perPath, settings)
+	}
+	merged.Settings = combineSettings(perPath)
+
+	return merged
+}
+
+// parentDirs returns the directories containing p, outermost first, without the root
+func parentDirs(p string) []string {
+	var dirs []string
+	for dir := path.Dir(filepath.ToSlash(p)); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		dirs = append([]string{dir}, dirs...)
+	}
+	return dirs
+}
+
+// overrideSettings returns base with every setting that layer sets replaced
+func overrideSettings(base, layer RuleSettings) RuleSettings {
+	if len(layer.Types) > 0 {
+		base.Types = layer.Types
+	}
+	if len(layer.Scopes) > 0 {
+		base.Scopes = layer.Scopes
+	}
+	if layer.MaxSubjectLength > 0 {
+		base.MaxSubjectLength = layer.MaxSubjectLength
+	}
+	if layer.BodyRequired {
+		base.BodyRequired = true
+	}
+	if len(layer.Footers) > 0 {
+		base.Footers = layer.Footers
+	}
+	if layer.Language != "" {
+		base.Language = layer.Language
+	}
+	if layer.MaxBodyLineLength > 0 {
+		base.MaxBodyLineLength = layer.MaxBodyLineLength
+	}
+	if layer.FromFrontMatter {
+		base.FromFrontMatter = true
+	}
+	return base
+}
+
+// combineSettings merges the settings of several paths into ones a single
+// message can satisfy: any type or scope allowed for one of the paths, the
+// shortest subject limit, and every required body and footer. A list one of
+// the paths leaves unrestricted stays unrestricted.
+func combineSettings(all []RuleSettings) RuleSettings {
+	if len(all) == 0 {
+		return RuleSettings{}
+	}
+
+	combined := RuleSettings{Types: all[0].Types, Scopes: all[0].Scopes}
+	for _, settings := range all {
+		combined.Types = unionRestriction(combined.Types, settings.Types)
+		combined.Scopes = unionRestriction(combined.Scopes, settings.Scopes)
+		combined.MaxSubjectLength = shortestLimit(combined.MaxSubjectLength, settings.MaxSubjectLength)
+		combined.MaxBodyLineLength = shortestLimit(combined.MaxBodyLineLength, settings.MaxBodyLineLength)
+		combined.BodyRequired = combined.BodyRequired || settings.BodyRequired
+		combined.FromFrontMatter = combined.FromFrontMatter || settings.FromFrontMatter
+		for _, footer := range settings.Footers {
+			combined.Footers = appendUnique(combined.Footers, footer)
+		}
+		if combined.Language == "" {
+			combined.Language = settings.Language
+		}
+	}
+	return combined
+}
+
+// shortestLimit returns the smaller of two limits where 0 means no limit
+func shortestLimit(a, b int) int {
+	if a == 0 || (b > 0 && b < a) {
+		return b
+	}
+	return a
+}
+
+// unionRestriction unions two allow-lists where an empty list allows anything
+func unionRestriction(a, b []string) []string {
+	if len(a) == 0 || len(b) == 0 {
+		return nil
+	}
+	union := append([]string(nil), a...)
+	for _, value := range b {
+		union = appendUnique(union, value)
+	}
+	return union
+}
+
+// files lists the rules files that contributed to r
+func (r AutocommitRules) files() string {
+	files := []string{r.Path}
+	if r.User != "" {
+		files = append(files, fmt.Sprintf("%s (%s)", r.User, r.UserMerge))
+	}
+	return strings.Join(append(files, r.Layers...), ", ")
+}
+
+// parseRules splits rules file content into its front matter settings and
+// the free-text rules the model is given as guidance. Invalid front matter
+// is reported and left out, the free text is still used.
+func parseRules(content, source, filePath string) AutocommitRules {
+	rules := AutocommitRules{Rules: content, Source: source, Path: filePath}
+
+	frontMatter, body, ok := splitFrontMatter(content)
+	if !ok {
+		return rules
+	}
+	rules.Rules = body
+
+	values, err := parseFrontMatter(frontMatter)
+	if err != nil {
+		fmt.Printf("Warning: Ignoring the front matter of %s: %v\n", filePath, err)
+		return rules
+	}
+	settings, err := settingsFromValues(values)
+	if err != nil {
+		fmt.Printf("Warning: Ignoring the front matter of %s: %v\n", filePath, err)
+		return rules
+	}
+
+	if settings.Merge != "" && source != "user" {
+		fmt.Printf("Warning: Ignoring the front matter of %s: merge is only supported in ~/%s/%s\n", filePath, config.ConfigDirName, config.UserRulesFileName)
+		return rules
+	}
+
+	rules.Settings = settings
+	rules.Settings.FromFrontMatter = true
+	return rules
+}
+
+// settingsFromValues converts parsed front matter into RuleSettings.
+// Unknown keys are rejected so a typo does not silently disable a check.
+func settingsFromValues(values map[string]any) (RuleSettings, error) {
+	var settings RuleSettings
+
+	// Visit keys in order so the first error reported is stable
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := values[key]
+		var err error
+		switch key {
+		case "types":
+			settings.Types, err = stringList(key, value)
+		case "scopes":
+			settings.Scopes, err = stringList(key, value)
+		case "footers":
+			settings.Footers, err = stringList(key, value)
+		case "max_subject_length":
+			settings.MaxSubjectLength, err = positiveNumber(key, value)
+		case "max_body_line_length":
+			settings.MaxBodyLineLength, err = positiveNumber(key, value)
+		case "merge":
+			merge, ok := value.(string)
+			if !ok || (merge != mergeAppend && merge != mergeOverride && merge != mergeDisable) {
+				err = fmt.Errorf("%s must be %s, %s or %s", key, mergeAppend, mergeOverride, mergeDisable)
+			}
+			settings.Merge = merge
+		case "require_body":
+			required, ok := value.(bool)
+			if !ok {
+				err = fmt.Errorf("%s must be true or false", key)
+			}
+			settings.BodyRequired = required
+		case "language":
+			language, ok := value.(string)
+			if !ok {
+				err = fmt.Errorf("%s must be a language name, e.g. English", key)
+			}
+			settings.Language = language
+		default:
+			err = fmt.Errorf("unknown setting %q (supported: types, scopes, max_subject_length, max_body_line_length, require_body, footers, language, merge)", key)
+		}
+		if err != nil {
+			return RuleSettings{}, err
+		}
+	}
+
+	return settings, nil
+}
+
+// positiveNumber accepts a number greater than zero for a limit setting
+func positiveNumber(key string, value any) (int, error) {
+	number, ok := value.(int)
+	if !ok || number <= 0 {
+		return 0, fmt.Errorf("%s must be a positive number", key)
+	}
+	return number, nil
+}
+
+// stringList accepts a list or a single value for a list setting
+func stringList(key string, value any) ([]string, error) {
+	switch v := value.(type) {
+	case []any:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			switch item.(type) {
+			case string, int, float64:
+				list = append(list, fmt.Sprint(item))
+			default:
+				return nil, fmt.Errorf("%s must be a list of names", key)
+			}
+		}
+		return list, nil
+	case string:
+		return []string{v}, nil
+	default:
+		return nil, fmt.Errorf("%s must be a list of names", key)
+	}
+}
+
+// guidance returns the rules text for the model, followed by the front
+// matter settings spelled out, so the model knows what it is checked against
+func (r AutocommitRules) guidance() string {
+	s := r.Settings
+	var requirements []string
+	if len(s.Types) > 0 {
+		requirements = append(requirements, fmt.Sprintf("- The type must be one of: %s", strings.Join(s.Types, ", ")))
+	}
+	if len(s.Scopes) > 0 {
+		requirements = append(requirements, fmt.Sprintf("- The scope, if any, must be one of: %s", strings.Join(s.Scopes, ", ")))
+	}
+	if s.MaxSubjectLength > 0 {
+		requirements = append(requirements, fmt.Sprintf("- The subject line must be at most %d characters long", s.MaxSubjectLength))
+	}
+	if s.MaxBodyLineLength > 0 {
+		requirements = append(requirements, fmt.Sprintf("- Wrap body lines at %d characters", s.MaxBodyLineLength))
+	}
+	if s.BodyRequired {
+		requirements = append(requirements, "- A body explaining the change is required, separated from the subject by a blank line")
+	}
+	if len(s.Footers) > 0 {
+		requirements = append(requirements, fmt.Sprintf("- End the message with these footers, each as \"Token: value\": %s", strings.Join(s.Footers, ", ")))
+	}
+	if s.Language != "" {
+		requirements = append(requirements, fmt.Sprintf("- Write the commit message in %s", s.Language))
+	}
+
+	if len(requirements) == 0 {
+		return r.Rules
+	}
+	return strings.TrimSpace(r.Rules) + "\n\nRequirements that are checked automatically:\n" + strings.Join(requirements, "\n")
+}
@@ -0,0 +1,223 @@
+package autocommit
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeRulesFile writes content to path, creating its directories
+func writeRulesFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetBaseRulesUserMerge(t *testing.T) {
+	const project = "---\nmax_subject_length: 50\n---\nProject rules.\n"
+
+	tests := []struct {
+		name    string
+		merge   string
+		project bool
+		// source is where the rules come from, user is the merged user file
+		source   string
+		user     bool
+		settings RuleSettings
+	}{
+		{
+			name:     "append",
+			merge:    "append",
+			project:  true,
+			source:   "project",
+			user:     true,
+			settings: RuleSettings{MaxSubjectLength: 50, MaxBodyLineLength: 72, FromFrontMatter: true},
+		},
+		{
+			name:     "override",
+			merge:    "override",
+			project:  true,
+			source:   "project",
+			user:     true,
+			settings: RuleSettings{MaxSubjectLength: 60, MaxBodyLineLength: 72, FromFrontMatter: true},
+		},
+		{
+			name:     "disable with project rules",
+			merge:    "disable",
+			project:  true,
+			source:   "project",
+			settings: RuleSettings{MaxSubjectLength: 50, FromFrontMatter: true},
+		},
+		{
+			name:     "disable without project rules",
+			merge:    "disable",
+			source:   "user",
+			settings: RuleSettings{MaxSubjectLength: 60, MaxBodyLineLength: 72, Merge: "disable", FromFrontMatter: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home, root := t.TempDir(), t.TempDir()
+			t.Setenv("HOME", home)
+			writeRulesFile(t, filepath.Join(home, ".gg", "autocommit.md"),
+				"---\nmerge: "+tt.merge+"\nmax_subject_length: 60\nmax_body_line_length: 72\n---\nWrap at 72.\n")
+			if tt.project {
+				writeRulesFile(t, filepath.Join(root, rulesFileName), project)
+			}
+
+			rules, err := getBaseRules(root)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rules.Source != tt.source {
+				t.Errorf("source = %q, want %q", rules.Source, tt.source)
+			}
+			if (rules.User != "") != tt.user {
+				t.Errorf("user file merged = %v, want %v", rules.User != "", tt.user)
+			}
+			if merged := strings.Contains(rules.Rules, "Wrap at 72."); merged != (tt.user || tt.source == "user") {
+				t.Errorf("rules text = %q", rules.Rules)
+			}
+			if !reflect.DeepEqual(rules.Settings, tt.settings) {
+				t.Errorf("settings = %+v, want %+v", rules.Settings, tt.settings)
+			}
+		})
+	}
+}
+
+func TestLayerDirectoryRules(t *testing.T) {
+	root := t.TempDir()
+	writeRulesFile(t, filepath.Join(root, "services", rulesFileName),
+		"---\ntypes: [feat, fix]\nmax_subject_length: 60\n---\nServices rules.\n")
+	writeRulesFile(t, filepath.Join(root, "services", "billing", rulesFileName),
+		"---\nscopes: [billing]\nmax_subject_length: 50\nrequire_body: true\n---\nBilling rules.\n")
+	writeRulesFile(t, filepath.Join(root, "services", "search", rulesFileName),
+		"---\ntypes: [perf]\nscopes: [search]\nfooters: [Refs]\n---\nSearch rules.\n")
+	writeRulesFile(t, filepath.Join(root, "web", rulesFileName),
+		"---\nmax_subject_length: 40\n---\nWeb rules.\n")
+
+	base := AutocommitRules{Rules: "Base rules.", Source: "project", Path: "base", Settings: RuleSettings{Types: []string{"feat", "fix", "docs"}, MaxSubjectLength: 72}}
+
+	tests := []struct {
+		name     string
+		paths    []string
+		layers   []string
+		text     []string
+		settings RuleSettings
+	}{
+		{
+			name:     "no rules files",
+			paths:    []string{"README.md", "docs/guide.md"},
+			text:     []string{"Base rules."},
+			settings: base.Settings,
+		},
+		{
+			name:   "parents before children",
+			paths:  []string{"services/billing/invoice.go"},
+			layers: []string{"services/.autocommit.md", "services/billing/.autocommit.md"},
+			text: []string{
+				"Base rules.",
+				"Additional rules for changes under services/:\nServices rules.",
+				"Additional rules for changes under services/billing/:\nBilling rules.",
+			},
+			settings: RuleSettings{Types: []string{"feat", "fix"}, Scopes: []string{"billing"}, MaxSubjectLength: 50, BodyRequired: true, FromFrontMatter: true},
+		},
+		{
+			name:   "allowed lists are united across paths",
+			paths:  []string{"services/billing/invoice.go", "services/search/index.go"},
+			layers: []string{"services/.autocommit.md", "services/billing/.autocommit.md", "services/search/.autocommit.md"},
+			text: []string{
+				"Base rules.",
+				"Additional rules for changes under services/:\nServices rules.",
+				"Additional rules for changes under services/billing/:\nBilling rules.",
+				"Additional rules for changes under services/search/:\nSearch rules.",
+			},
+			settings: RuleSettings{
+				Types:            []string{"feat", "fix", "perf"},
+				Scopes:           []string{"billing", "search"},
+				MaxSubjectLength: 50,
+				BodyRequired:     true,
+				Footers:          []string{"Refs"},
+				FromFrontMatter:  true,
+			},
+		},
+		{
+			name:   "a path without a list allows anything",
+			paths:  []string{"services/billing/invoice.go", "services/api.go"},
+			layers: []string{"services/.autocommit.md", "services/billing/.autocommit.md"},
+			text: []string{
+				"Base rules.",
+				"Additional rules for changes under services/:\nServices rules.",
+				"Additional rules for changes under services/billing/:\nBilling rules.",
+			},
+			settings: RuleSettings{Types: []string{"feat", "fix"}, MaxSubjectLength: 50, BodyRequired: true, FromFrontMatter: true},
+		},
+		{
+			name:   "shortest limit wins",
+			paths:  []string{"services/billing/invoice.go", "web/index.html", "README.md"},
+			layers: []string{"services/.autocommit.md", "web/.autocommit.md", "services/billing/.autocommit.md"},
+			text: []string{
+				"Base rules.",
+				"Additional rules for changes under services/:\nServices rules.",
+				"Additional rules for changes under web/:\nWeb rules.",
+				"Additional rules for changes under services/billing/:\nBilling rules.",
+			},
+			settings: RuleSettings{Types: []string{"feat", "fix", "docs"}, MaxSubjectLength: 40, BodyRequired: true, FromFrontMatter: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := layerDirectoryRules(base, root, tt.paths)
+			if !reflect.DeepEqual(rules.Layers, tt.layers) {
+				t.Errorf("layers = %v, want %v", rules.Layers, tt.layers)
+			}
+			if want := strings.Join(tt.text, "\n\n"); rules.Rules != want {
+				t.Errorf("rules text =\n%s\nwant\n%s", rules.Rules, want)
+			}
+			if !reflect.DeepEqual(rules.Settings, tt.settings) {
+				t.Errorf("settings =\n%+v\nwant\n%+v", rules.Settings, tt.settings)
+			}
+		})
+	}
+}
+
+func TestGetAutocommitRulesFromSubdirectory(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("HOME", t.TempDir())
+	if output, err := exec.Command("git", "init", "-q", root).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, output)
+	}
+	writeRulesFile(t, filepath.Join(root, rulesFileName), "---\nmax_subject_length: 60\n---\nProject rules.\n")
+	writeRulesFile(t, filepath.Join(root, "web", rulesFileName), "---\nscopes: [web]\n---\nWeb rules.\n")
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	if err := os.Chdir(filepath.Join(root, "web")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Paths are relative to the repository root, wherever gg runs
+	rules, err := getAutocommitRules([]string{"web/app.js"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rules.Source != "project" || !reflect.DeepEqual(rules.Layers, []string{"web/.autocommit.md"}) {
+		t.Errorf("rules from %s with layers %v, want the project rules with web/.autocommit.md", rules.Source, rules.Layers)
+	}
+	want := RuleSettings{Scopes: []string{"web"}, MaxSubjectLength: 60, FromFrontMatter: true}
+	if !reflect.DeepEqual(rules.Settings, want) {
+		t.Errorf("settings = %+v, want %+v", rules.Settings, want)
+	}
+}
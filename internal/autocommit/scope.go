@@ -0,0 +1,73 @@
+package autocommit
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/user/gitgud/internal/git"
+)
+
+// commitScope describes which changes gg ac describes and commits
+type commitScope struct {
+	// staged leaves unstaged and untracked changes out of the commit
+	staged bool
+	// amendBase is the commit HEAD is compared against when amending, empty otherwise
+	amendBase string
+}
+
+// diff returns the changes the commit message has to describe
+func (s commitScope) diff() (string, error) {
+	switch {
+	case s.amendBase != "":
+		return git.GetStagedDiffAgainst(s.amendBase)
+	case s.staged:
+		return git.GetStagedDiff()
+	default:
+		return git.GetGitDiff()
+	}
+}
+
+// diffStat returns the diffstat of the changes that will be committed
+func (s commitScope) diffStat() (string, error) {
+	switch {
+	case s.amendBase != "":
+		return git.GetStagedDiffStat(s.amendBase)
+	case s.staged:
+		return git.GetStagedDiffStat("")
+	default:
+		return git.GetDiffStat()
+	}
+}
+
+// paths returns the paths of the changes that will be committed, relative to the repository root
+func (s commitScope) paths() ([]string, error) {
+	switch {
+	case s.amendBase != "":
+		return git.GetStagedPaths(s.amendBase)
+	case s.staged:
+		return git.GetStagedPaths("")
+	default:
+		return git.GetChangedPaths()
+	}
+}
+
+// commit commits with message, staging every change first unless only the
+// staged changes should be committed
+func (s commitScope) commit(message string) error {
+	// Add all changes
+	if !s.staged && s.amendBase == "" {
+		addCmd := exec.Command("git", "add", ".")
+		addCmd.Stdout = os.Stdout
+		addCmd.Stderr = os.Stderr
+		if err := addCmd.Run(); err != nil {
+			return fmt.Errorf("error adding changes: %v", err)
+		}
+	}
+
+	// Commit changes
+	if s.amendBase != "" {
+		return git.ExecuteGitCommand("commit", "--amend", "-m", message)
+	}
+	return git.ExecuteGitCommand("commit", "-m", message)
+}
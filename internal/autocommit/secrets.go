@@ -0,0 +1,79 @@
+package autocommit
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+
+	"github.com/user/gitgud/internal/config"
+	"github.com/user/gitgud/internal/secrets"
+)
+
+// maxListedSecrets limits how many findings are printed
+const maxListedSecrets = 10
+
+// screenSecrets scans diffs for secrets before they are sent to the provider
+// and applies the configured secret policy. With the ask policy the user
+// chooses to redact, send or abort; without a reader (non-interactive runs)
+// the secrets are redacted. It returns the diffs to send, or errSecretsBlocked.
+func screenSecrets(diffs []string, reader *bufio.Reader) ([]string, error) {
+	// Content of files in .ggignore is never sent, so secrets in it do not matter
+	matcher := loadPromptIgnore()
+	var findings []secrets.Finding
+	for _, diff := range diffs {
+		for _, finding := range secrets.ScanDiff(diff) {
+			if finding.Path == "" || !matcher.Match(finding.Path) {
+				findings = append(findings, finding)
+			}
+		}
+	}
+	if len(findings) == 0 {
+		return diffs, nil
+	}
+
+	// List what was found without revealing it
+	fmt.Printf("\nWarning: Found %d possible secret(s) in the changes:\n", len(findings))
+	for i, finding := range findings {
+		if i == maxListedSecrets {
+			fmt.Printf("  ...and %d more\n", len(findings)-i)
+			break
+		}
+		path := finding.Path
+		if path == "" {
+			path = "(unknown file)"
+		}
+		fmt.Printf("  - %s: %s %s\n", path, finding.Kind, secrets.Mask(finding.Secret))
+	}
+	fmt.Println("Note: Redacting only affects what is sent to the provider, the secrets are still committed.")
+
+	policy := config.LoadConfig().SecretPolicy
+	switch policy {
+	case config.SecretPolicyBlock:
+		return nil, errSecretsBlocked
+	case config.SecretPolicyRedact:
+	case config.SecretPolicyAsk:
+		if reader == nil {
+			break
+		}
+		fmt.Print("Redact them before sending (r), send the diff as is (s) or abort (a)? [r]: ")
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("error reading input: %v", err)
+		}
+		switch strings.ToLower(strings.TrimSpace(line)) {
+		case "s", "send":
+			return diffs, nil
+		case "a", "abort":
+			return nil, errSecretsBlocked
+		}
+	default:
+		fmt.Printf("Warning: Unknown secret_policy %q, redacting.\n", policy)
+	}
+
+	fmt.Println("Redacting secrets before contacting the provider.")
+	redacted := make([]string, len(diffs))
+	for i, diff := range diffs {
+		redacted[i] = secrets.RedactDiff(diff)
+	}
+	return redacted, nil
+}
@@ -0,0 +1,289 @@
+package autocommit
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// maxParallelSummaries limits how many chunk summaries are requested at once
+	maxParallelSummaries = 4
+	// maxReduceRounds is how often summaries are combined before what still
+	// does not fit the diff budget is cut
+	maxReduceRounds = 4
+	// summariesHeader introduces the summaries that replace a very large diff
+	summariesHeader = "(The full diff is too large to include. Below are summaries of each part of it.)\n\n"
+)
+
+// summaryPart is the summary of one or more neighbouring chunks of a very
+// large diff, numbered from first to last
+type summaryPart struct {
+	first, last int
+	// files describes the files of a single chunk
+	files string
+	text  string
+}
+
+func (p summaryPart) String() string {
+	if p.first == p.last {
+		return fmt.Sprintf("Part %d (%s):\n%s\n\n", p.first, p.files, p.text)
+	}
+	return fmt.Sprintf("Parts %d-%d:\n%s\n\n", p.first, p.last, p.text)
+}
+
+// prepareDiff returns the diff section of a prompt. Diffs that fit the map-reduce
+// threshold are budgeted directly; anything larger is split into chunks that
+// are summarized separately, and the summaries replace the diff.
+func prepareDiff(ctx context.Context, gen generator, files []fileDiff, trailer string) (string, error) {
+	// Files listed in .ggignore are only named, their content is never sent
+	files, excluded := excludeIgnoredFiles(files, loadPromptIgnore())
+	trailer = excluded + trailer
+
+	total := estimateTokens(trailer)
+	for _, file := range files {
+		total += estimateTokens(file.Diff)
+	}
+
+	if total <= gen.settings.MapReduceTokens || len(files) < 2 {
+		return budgetDiff(files, trailer, gen.settings.DiffTokens), nil
+	}
+
+	chunks := chunkFiles(files, gen.settings.DiffTokens)
+	fmt.Printf("Diff is very large (~%d tokens), summarizing it in %d chunk(s)...\n", total, len(chunks))
+
+	summaries, err := summarizeChunks(ctx, gen, chunks)
+	if err != nil {
+		return "", fmt.Errorf("error summarizing diff: %v", err)
+	}
+
+	parts := make([]summaryPart, len(summaries))
+	for i, summary := range summaries {
+		parts[i] = summaryPart{first: i + 1, last: i + 1, files: describeChunk(chunks[i]), text: summary}
+	}
+
+	// The summaries get what the header and the trailer, cut to a quarter
+	// of the budget, leave
+	trailer = truncateLines(trailer, gen.settings.DiffTokens/4)
+	reduced, err := reduceSummaries(ctx, gen, parts, gen.settings.DiffTokens-estimateTokens(summariesHeader)-estimateTokens(trailer))
+	if err != nil {
+		return "", fmt.Errorf("error summarizing diff: %v", err)
+	}
+
+	return summariesHeader + reduced + trailer, nil
+}
+
+// reduceSummaries renders parts within maxTokens. While they do not fit,
+// neighbouring summaries are combined by the provider, at least halving
+// their number each round. What still does not fit after maxReduceRounds is cut.
+func reduceSummaries(ctx context.Context, gen generator, parts []summaryPart, maxTokens int) (string, error) {
+	for round := 1; round <= maxReduceRounds && len(parts) > 1 && summaryTokens(parts) > maxTokens; round++ {
+		groups := groupSummaries(parts, maxTokens)
+		fmt.Printf("Summaries are too large (~%d tokens), combining them into %d...\n", summaryTokens(parts), len(groups))
+
+		combined, err := combineSummaries(ctx, gen, groups)
+		if err != nil {
+			return "", err
+		}
+		parts = combined
+	}
+
+	var out strings.Builder
+	for _, part := range parts {
+		out.WriteString(part.String())
+	}
+	return truncateLines(out.String(), maxTokens), nil
+}
+
+// summaryTokens estimates the tokens parts take in the prompt
+func summaryTokens(parts []summaryPart) int {
+	total := 0
+	for _, part := range parts {
+		total += estimateTokens(part.String())
+	}
+	return total
+}
+
+// groupSummaries splits parts into runs of neighbours of up to maxTokens.
+// Every run but a trailing one has at least two parts, so combining them
+// always makes progress.
+func groupSummaries(parts []summaryPart, maxTokens int) [][]summaryPart {
+	var groups [][]summaryPart
+	var current []summaryPart
+	used := 0
+	for _, part := range parts {
+		tokens := estimateTokens(part.String())
+		if len(current) >= 2 && used+tokens > maxTokens {
+			groups = append(groups, current)
+			current, used = nil, 0
+		}
+		current = append(current, part)
+		used += tokens
+	}
+	if len(current) > 0 {
+		groups = append(groups, current)
+	}
+	return groups
+}
+
+// combineSummaries asks the provider to merge each group of summaries into
+// one, in parallel. A group of one is kept as it is. Combined summaries are
+// remembered by the generator like chunk summaries.
+func combineSummaries(ctx context.Context, gen generator, groups [][]summaryPart) ([]summaryPart, error) {
+	combined := make([]summaryPart, len(groups))
+	errs := make([]error, len(groups))
+	sem := make(chan struct{}, maxParallelSummaries)
+	var wg sync.WaitGroup
+
+	for i, group := range groups {
+		if len(group) == 1 {
+			combined[i] = group[0]
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, group []summaryPart) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			var summaries strings.Builder
+			for _, part := range group {
+				summaries.WriteString(part.String())
+			}
+			prompt := "Combine these summaries of parts of one large change into a single short bullet list " +
+				"of the notable changes and their purpose. Reply with the list only, nothing else.\n\n" +
+				summaries.String()
+
+			text, err := rememberedSummary(gen, prompt, func() (string, error) {
+				return gen.quiet().complete(ctx, prompt)
+			})
+			combined[i], errs[i] = summaryPart{first: group[0].first, last: group[len(group)-1].last, text: text}, err
+		}(i, group)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return combined, nil
+}
+
+// rememberedSummary returns the summary the generator remembers for key,
+// or asks for it with summarize and remembers it
+func rememberedSummary(gen generator, key string, summarize func() (string, error)) (string, error) {
+	if cached, ok := gen.summaries.Load(key); ok {
+		return cached.(string), nil
+	}
+	summary, err := summarize()
+	if err != nil {
+		return "", err
+	}
+	gen.summaries.Store(key, summary)
+	return summary, nil
+}
+
+// chunkFiles groups file diffs by directory into chunks of roughly maxTokens.
+// A single file larger than maxTokens gets a chunk of its own and is
+// budgeted when the chunk is summarized.
+func chunkFiles(files []fileDiff, maxTokens int) [][]fileDiff {
+	sorted := make([]fileDiff, len(files))
+	copy(sorted, files)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return path.Dir(sorted[i].Path) < path.Dir(sorted[j].Path)
+	})
+
+	var chunks [][]fileDiff
+	var current []fileDiff
+	used := 0
+	for _, file := range sorted {
+		tokens := estimateTokens(file.Diff)
+		if len(current) > 0 && used+tokens > maxTokens {
+			chunks = append(chunks, current)
+			current, used = nil, 0
+		}
+		current = append(current, file)
+		used += tokens
+	}
+	if len(current) > 0 {
+		chunks = append(chunks, current)
+	}
+
+	return chunks
+}
+
+// summarizeChunks summarizes every chunk in parallel and returns the
+// summaries in chunk order. Summaries are remembered by the generator so a
+// retry does not pay for the map step again.
+func summarizeChunks(ctx context.Context, gen generator, chunks [][]fileDiff) ([]string, error) {
+	summaries := make([]string, len(chunks))
+	errs := make([]error, len(chunks))
+	sem := make(chan struct{}, maxParallelSummaries)
+	var wg sync.WaitGroup
+
+	for i, chunk := range chunks {
+		wg.Add(1)
+		go func(i int, chunk []fileDiff) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			summaries[i], errs[i] = summarizeChunk(ctx, gen, chunk)
+		}(i, chunk)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return summaries, nil
+}
+
+// summarizeChunk asks the provider for a short summary of one chunk of the diff
+func summarizeChunk(ctx context.Context, gen generator, chunk []fileDiff) (string, error) {
+	diffContent := budgetDiff(chunk, "", gen.settings.DiffTokens)
+
+	filenames := make([]string, len(chunk))
+	for i, file := range chunk {
+		filenames[i] = file.Path
+	}
+
+	prompt := fmt.Sprintf(
+		"Summarize the following changes to these %d files: %s\n\n"+
+			"Git diff for these files:\n%s\n\n"+
+			"The summary will be combined with summaries of other parts of the same change "+
+			"to write a single commit message. Reply with a short bullet list of the notable "+
+			"changes and their purpose, nothing else.",
+		len(filenames),
+		strings.Join(filenames, ", "),
+		diffContent,
+	)
+
+	return rememberedSummary(gen, diffContent, func() (string, error) {
+		return gen.quiet().complete(ctx, prompt)
+	})
+}
+
+// describeChunk names a chunk by its file count and directories
+func describeChunk(chunk []fileDiff) string {
+	seen := make(map[string]bool)
+	var dirs []string
+	for _, file := range chunk {
+		dir := path.Dir(file.Path)
+		if !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	if len(dirs) > 3 {
+		dirs = append(dirs[:3], "...")
+	}
+	return fmt.Sprintf("%d file(s) in %s", len(chunk), strings.Join(dirs, ", "))
+}
@@ -0,0 +1,95 @@
+package autocommit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/user/gitgud/internal/config"
+	"github.com/user/gitgud/internal/provider"
+)
+
+// fakeSummarizer answers chunk summary requests with long summaries and
+// requests to combine summaries with short ones
+type fakeSummarizer struct {
+	chunkRequests, combineRequests atomic.Int32
+}
+
+func (f *fakeSummarizer) Name() string         { return "fake" }
+func (f *fakeSummarizer) DefaultModel() string { return "fake" }
+
+func (f *fakeSummarizer) Complete(ctx context.Context, req provider.Request) (string, error) {
+	if strings.HasPrefix(req.Messages[0].Content, "Combine") {
+		f.combineRequests.Add(1)
+		return strings.Repeat("- combined change\n", 10), nil
+	}
+	f.chunkRequests.Add(1)
+	return strings.Repeat("- notable change\n", 70), nil
+}
+
+func (f *fakeSummarizer) Stream(ctx context.Context, req provider.Request, onToken func(string)) (string, error) {
+	return f.Complete(ctx, req)
+}
+
+func TestPrepareDiffBudgetsSummaries(t *testing.T) {
+	// 200 files of ~530 tokens in 200 directories give a chunk each, whose
+	// summaries of ~300 tokens are far over the diff budget together
+	var files []fileDiff
+	for i := 0; i < 200; i++ {
+		files = append(files, fileWithChange(fmt.Sprintf("dir%03d/file.go", i), strings.Repeat("o", 1000), strings.Repeat("n", 1000)))
+	}
+
+	llm := &fakeSummarizer{}
+	gen := generator{
+		llm:       llm,
+		settings:  config.GenerationSettings{DiffTokens: 1000, MapReduceTokens: 2000},
+		summaries: &sync.Map{},
+	}
+
+	out, err := prepareDiff(context.Background(), gen, files, "trailer\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if tokens := estimateTokens(out); tokens > 1000 {
+		t.Errorf("prompt diff is %d tokens, over the budget of 1000:\n%s", tokens, out)
+	}
+	if !strings.HasSuffix(out, "trailer\n") {
+		t.Errorf("trailer is missing:\n%s", out)
+	}
+	if !strings.Contains(out, "combined change") {
+		t.Errorf("summaries were not combined:\n%s", out)
+	}
+	if llm.chunkRequests.Load() != 200 {
+		t.Errorf("%d chunk summaries requested, want 200", llm.chunkRequests.Load())
+	}
+
+	// A retry reuses every summary
+	chunks, combines := llm.chunkRequests.Load(), llm.combineRequests.Load()
+	if _, err := prepareDiff(context.Background(), gen, files, "trailer\n"); err != nil {
+		t.Fatal(err)
+	}
+	if llm.chunkRequests.Load() != chunks || llm.combineRequests.Load() != combines {
+		t.Errorf("retry requested summaries again")
+	}
+}
+
+func TestGroupSummaries(t *testing.T) {
+	parts := make([]summaryPart, 5)
+	for i := range parts {
+		parts[i] = summaryPart{first: i + 1, last: i + 1, files: "1 file(s) in x", text: strings.Repeat("a", 400)}
+	}
+
+	// Every part alone is over the limit, yet pairs are still formed
+	groups := groupSummaries(parts, 50)
+	var sizes []int
+	for _, group := range groups {
+		sizes = append(sizes, len(group))
+	}
+	if fmt.Sprint(sizes) != "[2 2 1]" {
+		t.Errorf("group sizes = %v, want [2 2 1]", sizes)
+	}
+}
@@ -0,0 +1,84 @@
+package autocommit
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/user/gitgud/internal/config"
+	"github.com/user/gitgud/internal/git"
+	"github.com/user/gitgud/internal/ui"
+)
+
+// recentAuthorCommits is how far back the co-author picker looks for authors
+const recentAuthorCommits = 500
+
+// identityPattern matches a "Name <email>" identity
+var identityPattern = regexp.MustCompile(`^[^<>]+ <[^<>\s]+@[^<>\s]+>$`)
+
+// pickCoAuthors asks which recent authors worked on the commit and adds them
+// to opts.CoAuthors. It returns errCoAuthorSelectionExited when the user exits.
+func pickCoAuthors(opts *Options) error {
+	authors, err := git.GetRecentAuthors(recentAuthorCommits)
+	if err != nil {
+		return err
+	}
+
+	// Authors given with --co-author are already on the commit
+	var choices []string
+	for _, author := range authors {
+		if !containsLine(opts.CoAuthors, author) {
+			choices = append(choices, author)
+		}
+	}
+	if len(choices) == 0 {
+		fmt.Println("No other authors found in the recent history to pick co-authors from.")
+		return nil
+	}
+
+	fmt.Println()
+	selected, err := ui.SelectCoAuthors(choices)
+	if err != nil {
+		if strings.Contains(err.Error(), "user chose to exit") {
+			return errCoAuthorSelectionExited
+		}
+		return err
+	}
+	opts.CoAuthors = append(opts.CoAuthors, selected...)
+	return nil
+}
+
+// commitTrailers returns the trailers added to every generated message:
+// co-authors, the AI attribution and the sign-off, in that order.
+// Offline messages get no AI attribution.
+func commitTrailers(opts Options, gen generator) ([]string, error) {
+	cfg := config.LoadConfig()
+	var trailers []string
+
+	for _, coAuthor := range opts.CoAuthors {
+		coAuthor = strings.TrimSpace(coAuthor)
+		if !identityPattern.MatchString(coAuthor) {
+			return nil, fmt.Errorf("co-author %q must have the form \"Name <email>\"", coAuthor)
+		}
+		trailers = append(trailers, "Co-authored-by: "+coAuthor)
+	}
+
+	if cfg.AITrailer != "" && !opts.Offline {
+		trailer := strings.NewReplacer("{model}", gen.model(), "{provider}", gen.llm.Name()).Replace(cfg.AITrailer)
+		trailers = append(trailers, trailer)
+	}
+
+	signOff := cfg.SignOff
+	if opts.SignOff != nil {
+		signOff = *opts.SignOff
+	}
+	if signOff {
+		identity, err := git.GetIdentity()
+		if err != nil {
+			return nil, err
+		}
+		trailers = append(trailers, "Signed-off-by: "+identity)
+	}
+
+	return trailers, nil
+}
@@ -0,0 +1,71 @@
+package autocommit
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/user/gitgud/internal/config"
+)
+
+func TestCommitTrailers(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("GIT_COMMITTER_NAME", "John Roe")
+	t.Setenv("GIT_COMMITTER_EMAIL", "john@example.com")
+	if err := os.MkdirAll(filepath.Join(home, ".gg"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".gg", "config.json"), []byte(`{"signoff": true, "ai_trailer": "AI-Assisted: {model} via {provider}"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	gen := generator{llm: &fakeSummarizer{}, settings: config.GenerationSettings{Model: "small-model"}}
+	no := false
+
+	tests := []struct {
+		name    string
+		opts    Options
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "co-authors, attribution and sign-off in order",
+			opts: Options{CoAuthors: []string{" Jane Doe <jane@example.com> ", "Sam Poe <sam@example.com>"}},
+			want: []string{
+				"Co-authored-by: Jane Doe <jane@example.com>",
+				"Co-authored-by: Sam Poe <sam@example.com>",
+				"AI-Assisted: small-model via fake",
+				"Signed-off-by: John Roe <john@example.com>",
+			},
+		},
+		{
+			name: "offline messages get no attribution",
+			opts: Options{Offline: true},
+			want: []string{"Signed-off-by: John Roe <john@example.com>"},
+		},
+		{
+			name: "sign-off turned off on the command line",
+			opts: Options{SignOff: &no},
+			want: []string{"AI-Assisted: small-model via fake"},
+		},
+		{
+			name:    "co-author without an email",
+			opts:    Options{CoAuthors: []string{"Jane Doe"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := commitTrailers(tt.opts, gen)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("commitTrailers error = %v, want error %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("commitTrailers =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
@@ -16,30 +18,232 @@ import (
 
 // Constant for config directory and file names
 const (
-	ConfigDirName  = ".gg"
-	ConfigFileName = "config.json"
+	ConfigDirName     = ".gg"
+	ConfigFileName    = "config.json"
+	CacheDirName      = "cache"
+	UserRulesFileName = "autocommit.md"
 )
 
+// Supported LLM provider names
+const (
+	ProviderOpenAI           = "openai"
+	ProviderOpenAICompatible = "openai-compatible"
+	ProviderOllama           = "ollama"
+	ProviderLlamaCpp         = "llamacpp"
+	ProviderAnthropic        = "anthropic"
+)
+
+// Policies for secrets found in a diff before it is sent to a provider
+const (
+	// SecretPolicyAsk asks whether to redact, send or abort, and redacts when nobody can be asked
+	SecretPolicyAsk = "ask"
+	// SecretPolicyRedact replaces secrets with a marker
+	SecretPolicyRedact = "redact"
+	// SecretPolicyBlock refuses to send the diff
+	SecretPolicyBlock = "block"
+)
+
+// Command names used for per-command generation settings
+const (
+	CommandAutocommit        = "ac"
+	CommandAutocommitPerFile = "acpf"
+)
+
+// Defaults used when no generation settings are configured
+const (
+	// DefaultMaxTokens is the completion token limit
+	DefaultMaxTokens = 250
+	// DefaultDiffTokens is the estimated token budget for the diff in a prompt
+	DefaultDiffTokens = 3000
+	// DefaultMapReduceTokens is the diff size above which the diff is summarized in chunks
+	DefaultMapReduceTokens = 12000
+)
+
+// DefaultMultiScope is the scope used when the changed paths map to several scopes
+const DefaultMultiScope = "multi"
+
+// ScopeMapping maps the paths matching a pattern in gitignore syntax to a commit scope
+type ScopeMapping struct {
+	Path  string `json:"path"`
+	Scope string `json:"scope"`
+}
+
+// Where ticket references go in a commit message
+const (
+	TicketPlacementFooter  = "footer"
+	TicketPlacementSubject = "subject"
+)
+
+// TicketSettings adds the ticket IDs found in the branch name to every
+// generated message. An empty Pattern turns it off.
+type TicketSettings struct {
+	// Pattern is matched against the branch name. Its first group, or the
+	// whole match when it has none, is the ticket ID.
+	Pattern string `json:"pattern,omitempty"`
+	// Format renders a reference with {id} replaced by the ID, e.g. "Closes #{id}"
+	Format string `json:"format,omitempty"`
+	// Placement is TicketPlacementFooter or TicketPlacementSubject
+	Placement string `json:"placement,omitempty"`
+}
+
+// Validate rejects settings that would otherwise be silently ignored
+func (t TicketSettings) Validate() error {
+	switch t.Placement {
+	case "", TicketPlacementFooter, TicketPlacementSubject:
+		return nil
+	default:
+		return fmt.Errorf("ticket.placement must be %s or %s, not %q", TicketPlacementFooter, TicketPlacementSubject, t.Placement)
+	}
+}
+
+// GenerationSettings controls how commit messages are generated.
+// Empty fields fall back to the next, less specific level.
+type GenerationSettings struct {
+	Model       string   `json:"model,omitempty"`
+	MaxTokens   int      `json:"max_tokens,omitempty"`
+	Temperature *float32 `json:"temperature,omitempty"`
+	DiffTokens  int      `json:"diff_tokens,omitempty"`
+	// MapReduceTokens is the estimated diff size above which the diff is
+	// summarized chunk by chunk before generating the message
+	MapReduceTokens int `json:"map_reduce_tokens,omitempty"`
+}
+
 // Config structure to store the application configuration
 type Config struct {
-	OpenAIAPIKey string `json:"openai_api_key"`
+	OpenAIAPIKey    string `json:"openai_api_key"`
+	AnthropicAPIKey string `json:"anthropic_api_key,omitempty"`
+	Provider        string `json:"provider,omitempty"`
+	BaseURL         string `json:"base_url,omitempty"`
+
+	// Staged makes gg ac use and commit only the staged changes by default
+	Staged bool `json:"staged,omitempty"`
+	// SecretPolicy decides what happens to secrets found in a diff: ask, redact or block
+	SecretPolicy string `json:"secret_policy,omitempty"`
+	// ScopeMap sets the scope of generated messages from the changed paths, the first matching pattern wins
+	ScopeMap []ScopeMapping `json:"scope_map,omitempty"`
+	// MultiScope is the scope used when the changed paths map to several scopes
+	MultiScope string `json:"multi_scope,omitempty"`
+	// Ticket references the ticket named in the branch name
+	Ticket TicketSettings `json:"ticket,omitempty"`
+	// SignOff adds a Signed-off-by trailer to generated messages, as git commit -s does
+	SignOff bool `json:"signoff,omitempty"`
+	// AITrailer is added to messages a model wrote, e.g. "AI-Assisted: {model}", empty for none
+	AITrailer string `json:"ai_trailer,omitempty"`
+
+	GenerationSettings
+	Commands map[string]GenerationSettings `json:"commands,omitempty"`
 }
 
-func GetOpenAIAPIKey() (string, error) {
+// GenerationFor resolves the generation settings for a command. Settings are
+// layered from the built-in defaults, the global config, the per-command
+// config and finally the command line overrides.
+func (c Config) GenerationFor(command string, overrides GenerationSettings) GenerationSettings {
+	settings := GenerationSettings{
+		MaxTokens:       DefaultMaxTokens,
+		DiffTokens:      DefaultDiffTokens,
+		MapReduceTokens: DefaultMapReduceTokens,
+	}
+	settings = settings.merge(c.GenerationSettings)
+	settings = settings.merge(c.Commands[command])
+	return settings.merge(overrides)
+}
+
+// merge returns s with every field that is set in other replaced
+func (s GenerationSettings) merge(other GenerationSettings) GenerationSettings {
+	if other.Model != "" {
+		s.Model = other.Model
+	}
+	if other.MaxTokens > 0 {
+		s.MaxTokens = other.MaxTokens
+	}
+	if other.Temperature != nil {
+		s.Temperature = other.Temperature
+	}
+	if other.DiffTokens > 0 {
+		s.DiffTokens = other.DiffTokens
+	}
+	if other.MapReduceTokens > 0 {
+		s.MapReduceTokens = other.MapReduceTokens
+	}
+	return s
+}
+
+// LoadConfig returns the effective configuration. The home directory config
+// takes priority over the one next to the executable, and the GG_PROVIDER and
+// GG_BASE_URL environment variables override the provider settings of both.
+func LoadConfig() Config {
+	cfg, err := getUserHomeConfig()
+	if err != nil {
+		if exePath, exeErr := os.Executable(); exeErr == nil {
+			cfg, _ = loadConfig(filepath.Dir(exePath))
+		}
+	}
+
+	if provider := os.Getenv("GG_PROVIDER"); provider != "" {
+		cfg.Provider = provider
+	}
+	if baseURL := os.Getenv("GG_BASE_URL"); baseURL != "" {
+		cfg.BaseURL = baseURL
+	}
+	if cfg.Provider == "" {
+		cfg.Provider = ProviderOpenAI
+	}
+	if cfg.SecretPolicy == "" {
+		cfg.SecretPolicy = SecretPolicyAsk
+	}
+	if cfg.MultiScope == "" {
+		cfg.MultiScope = DefaultMultiScope
+	}
+	if cfg.Ticket.Placement == "" {
+		cfg.Ticket.Placement = TicketPlacementFooter
+	}
+	if cfg.Ticket.Format == "" {
+		cfg.Ticket.Format = "Refs: {id}"
+		if cfg.Ticket.Placement == TicketPlacementSubject {
+			cfg.Ticket.Format = "{id}"
+		}
+	}
+
+	return cfg
+}
+
+// GetAnthropicAPIKey looks up the Anthropic API key from the environment,
+// a local .env file and the user's home config, in that order
+func GetAnthropicAPIKey() (string, error) {
+	if apiKey := os.Getenv("ANTHROPIC_API_KEY"); apiKey != "" {
+		return apiKey, nil
+	}
+
+	if err := godotenv.Load(); err == nil {
+		if apiKey := os.Getenv("ANTHROPIC_API_KEY"); apiKey != "" {
+			return apiKey, nil
+		}
+	}
+
+	homeConfig, err := getUserHomeConfig()
+	if err == nil && homeConfig.AnthropicAPIKey != "" {
+		return homeConfig.AnthropicAPIKey, nil
+	}
+
+	return "", fmt.Errorf("no Anthropic API key found; set ANTHROPIC_API_KEY or add anthropic_api_key to ~/%s/%s", ConfigDirName, ConfigFileName)
+}
+
+// GetOpenAIAPIKey returns the first OpenAI API key found. Keys are not checked
+// against the API here; keys that were recently rejected are skipped, and a key
+// rejected during generation is reported through MarkAPIKeyInvalid. When no
+// key is found, the user is asked for one if interactive is set; otherwise an
+// error is returned instead of waiting for input.
+func GetOpenAIAPIKey(interactive bool) (string, error) {
 	// Try multiple sources for the API key in order of priority
 
 	// 1. Check environment variable first
 	apiKey := os.Getenv("OPENAI_API_KEY")
 	if apiKey != "" {
-		// Validate the key
-		valid, err := ValidateAPIKey(apiKey)
-		if valid {
+		if !isKnownInvalidAPIKey(apiKey) {
 			return apiKey, nil
 		}
 		// If environment variable contains invalid key, report it but continue searching
-		if err != nil {
-			fmt.Printf("Warning: Environment variable OPENAI_API_KEY is invalid: %v\n", err)
-		}
+		fmt.Println("Warning: Environment variable OPENAI_API_KEY is invalid")
 	}
 
 	// 2. Check .env file in current directory
@@ -47,28 +251,20 @@ func GetOpenAIAPIKey() (string, error) {
 	if err == nil {
 		apiKey = os.Getenv("OPENAI_API_KEY")
 		if apiKey != "" {
-			// Validate the key
-			valid, err := ValidateAPIKey(apiKey)
-			if valid {
+			if !isKnownInvalidAPIKey(apiKey) {
 				return apiKey, nil
 			}
-			if err != nil {
-				fmt.Printf("Warning: API key in .env file is invalid: %v\n", err)
-			}
+			fmt.Println("Warning: API key in .env file is invalid")
 		}
 	}
 
 	// 3. Check user's home directory for config
 	homeConfig, err := getUserHomeConfig()
 	if err == nil && homeConfig.OpenAIAPIKey != "" {
-		// Validate the key
-		valid, err := ValidateAPIKey(homeConfig.OpenAIAPIKey)
-		if valid {
+		if !isKnownInvalidAPIKey(homeConfig.OpenAIAPIKey) {
 			return homeConfig.OpenAIAPIKey, nil
 		}
-		if err != nil {
-			fmt.Printf("Warning: API key in home config is invalid: %v\n", err)
-		}
+		fmt.Println("Warning: API key in home config is invalid")
 	}
 
 	// 4. Check executable directory for .env or config
@@ -80,30 +276,26 @@ func GetOpenAIAPIKey() (string, error) {
 		_ = godotenv.Load(filepath.Join(exeDir, ".env"))
 		apiKey = os.Getenv("OPENAI_API_KEY")
 		if apiKey != "" {
-			// Validate the key
-			valid, err := ValidateAPIKey(apiKey)
-			if valid {
+			if !isKnownInvalidAPIKey(apiKey) {
 				return apiKey, nil
 			}
-			if err != nil {
-				fmt.Printf("Warning: API key in executable directory .env file is invalid: %v\n", err)
-			}
+			fmt.Println("Warning: API key in executable directory .env file is invalid")
 		}
 
 		// Try config.json in exe dir
 		exeConfig, err := loadConfig(exeDir)
 		if err == nil && exeConfig.OpenAIAPIKey != "" {
-			// Validate the key
-			valid, err := ValidateAPIKey(exeConfig.OpenAIAPIKey)
-			if valid {
+			if !isKnownInvalidAPIKey(exeConfig.OpenAIAPIKey) {
 				return exeConfig.OpenAIAPIKey, nil
 			}
-			if err != nil {
-				fmt.Printf("Warning: API key in executable directory config is invalid: %v\n", err)
-			}
+			fmt.Println("Warning: API key in executable directory config is invalid")
 		}
 	}
 
+	if !interactive {
+		return "", fmt.Errorf("no valid OpenAI API key found; set OPENAI_API_KEY or add openai_api_key to ~/%s/%s", ConfigDirName, ConfigFileName)
+	}
+
 	fmt.Println("No valid OpenAI API key found.")
 	fmt.Println("You can:\n1. Run 'gg config' to set or update your API key\n2. Provide a key for this session")
 
@@ -111,12 +303,22 @@ func GetOpenAIAPIKey() (string, error) {
 	return setupConfigInteractively()
 }
 
-// ValidateAPIKey checks if the provided API key is valid by making a small request to OpenAI
+// ValidateAPIKey checks if the provided API key is valid by listing the
+// available models, which costs no tokens. Results are cached for a while so
+// repeated checks do not hit the API.
 func ValidateAPIKey(apiKey string) (bool, error) {
 	if apiKey == "" {
 		return false, fmt.Errorf("API key is empty")
 	}
 
+	// Reuse a recent result for the same key
+	if valid, ok := cachedAPIKeyValidation(apiKey); ok {
+		if !valid {
+			return false, fmt.Errorf("invalid API key")
+		}
+		return true, nil
+	}
+
 	// Create a client with a short timeout
 	client := openai.NewClient(apiKey)
 
@@ -124,37 +326,57 @@ func ValidateAPIKey(apiKey string) (bool, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	// Make a minimal request to validate the key
-	resp, err := client.CreateChatCompletion(
-		ctx,
-		openai.ChatCompletionRequest{
-			Model: openai.GPT3Dot5Turbo,
-			Messages: []openai.ChatCompletionMessage{
-				{
-					Role:    openai.ChatMessageRoleUser,
-					Content: "Test",
-				},
-			},
-			MaxTokens: 5,
-		},
-	)
-
+	// Listing models is the cheapest authenticated request
+	_, err := client.ListModels(ctx)
 	if err != nil {
-		if strings.Contains(err.Error(), "401") ||
-			strings.Contains(err.Error(), "invalid_api_key") ||
-			strings.Contains(err.Error(), "Incorrect API key") {
+		if IsUnauthorized(err) {
+			storeAPIKeyValidation(apiKey, false)
 			return false, fmt.Errorf("invalid API key")
 		}
 		// Could be a network error, but the key might still be valid
 		return false, fmt.Errorf("could not validate: %v", err)
 	}
 
-	// If we get a response, the key is valid
-	if len(resp.Choices) > 0 {
-		return true, nil
+	storeAPIKeyValidation(apiKey, true)
+	return true, nil
+}
+
+// IsUnauthorized reports whether err is an OpenAI authentication failure
+func IsUnauthorized(err error) bool {
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) && apiErr.HTTPStatusCode == http.StatusUnauthorized {
+		return true
+	}
+	var reqErr *openai.RequestError
+	if errors.As(err, &reqErr) && reqErr.HTTPStatusCode == http.StatusUnauthorized {
+		return true
 	}
+	return strings.Contains(err.Error(), "invalid_api_key") ||
+		strings.Contains(err.Error(), "Incorrect API key")
+}
 
-	return false, fmt.Errorf("unexpected response from API")
+// CacheDir returns the directory for cached data (~/.gg/cache), creating it if needed
+func CacheDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	cacheDir := filepath.Join(homeDir, ConfigDirName, CacheDirName)
+	if err := os.MkdirAll(cacheDir, 0700); err != nil { // Restrict to user only
+		return "", err
+	}
+
+	return cacheDir, nil
+}
+
+// UserRulesPath returns the path of the user's personal commit message rules (~/.gg/autocommit.md)
+func UserRulesPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ConfigDirName, UserRulesFileName), nil
 }
 
 func getUserHomeConfig() (Config, error) {
@@ -234,7 +456,10 @@ func setupConfigInteractively() (string, error) {
 		}
 
 		configDir := filepath.Join(homeDir, ConfigDirName)
-		config := Config{OpenAIAPIKey: apiKey}
+
+		// Keep any provider settings already stored in the home config
+		config, _ := loadConfig(configDir)
+		config.OpenAIAPIKey = apiKey
 
 		err = saveConfig(config, configDir)
 		if err != nil {
@@ -281,6 +506,30 @@ func HandleConfig() {
 func ShowConfigStatus() {
 	fmt.Println("Current Configuration:")
 
+	// Provider settings
+	cfg := LoadConfig()
+	if cfg.BaseURL != "" {
+		fmt.Printf("- Provider: %s (base URL: %s)\n", cfg.Provider, cfg.BaseURL)
+	} else {
+		fmt.Printf("- Provider: %s\n", cfg.Provider)
+	}
+	fmt.Printf("- Secret policy: %s\n", cfg.SecretPolicy)
+	if cfg.Ticket.Pattern != "" {
+		fmt.Printf("- Ticket pattern: %s (%s as %q)\n", cfg.Ticket.Pattern, cfg.Ticket.Placement, cfg.Ticket.Format)
+	}
+	if cfg.SignOff {
+		fmt.Println("- Sign-off: on")
+	}
+	if cfg.AITrailer != "" {
+		fmt.Printf("- AI trailer: %s\n", cfg.AITrailer)
+	}
+	if len(cfg.ScopeMap) > 0 {
+		fmt.Println("- Scope map:")
+		for _, mapping := range cfg.ScopeMap {
+			fmt.Printf("    %s -> %s\n", mapping.Path, mapping.Scope)
+		}
+	}
+
 	// Check all possible locations for API keys
 
 	// Environment variable
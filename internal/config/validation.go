@@ -0,0 +1,90 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// validationFileName stores API key validation results inside the cache directory
+	validationFileName = "api_keys.json"
+	// validationTTL is how long a validation result is trusted
+	validationTTL = 24 * time.Hour
+)
+
+// apiKeyValidation is a cached validation result. Keys are stored as hashes only.
+type apiKeyValidation struct {
+	Valid     bool      `json:"valid"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// MarkAPIKeyInvalid records that the API rejected apiKey, so the key lookup
+// skips it until the validation TTL expires
+func MarkAPIKeyInvalid(apiKey string) {
+	storeAPIKeyValidation(apiKey, false)
+}
+
+// isKnownInvalidAPIKey reports whether apiKey was rejected recently
+func isKnownInvalidAPIKey(apiKey string) bool {
+	valid, ok := cachedAPIKeyValidation(apiKey)
+	return ok && !valid
+}
+
+// cachedAPIKeyValidation returns a recent validation result for apiKey, if any
+func cachedAPIKeyValidation(apiKey string) (bool, bool) {
+	results := loadAPIKeyValidations()
+	result, ok := results[hashAPIKey(apiKey)]
+	if !ok || time.Since(result.CheckedAt) > validationTTL {
+		return false, false
+	}
+	return result.Valid, true
+}
+
+func storeAPIKeyValidation(apiKey string, valid bool) {
+	cacheDir, err := CacheDir()
+	if err != nil {
+		return
+	}
+
+	results := loadAPIKeyValidations()
+	for hash, result := range results {
+		if time.Since(result.CheckedAt) > validationTTL {
+			delete(results, hash)
+		}
+	}
+	results[hashAPIKey(apiKey)] = apiKeyValidation{Valid: valid, CheckedAt: time.Now()}
+
+	data, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return
+	}
+	_ = os.WriteFile(filepath.Join(cacheDir, validationFileName), data, 0600) // Restrict to user only
+}
+
+func loadAPIKeyValidations() map[string]apiKeyValidation {
+	results := make(map[string]apiKeyValidation)
+
+	cacheDir, err := CacheDir()
+	if err != nil {
+		return results
+	}
+
+	data, err := os.ReadFile(filepath.Join(cacheDir, validationFileName))
+	if err != nil {
+		return results
+	}
+
+	if err := json.Unmarshal(data, &results); err != nil {
+		return make(map[string]apiKeyValidation)
+	}
+	return results
+}
+
+func hashAPIKey(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:])
+}
@@ -59,28 +59,175 @@ func GetGitDiff() (string, error) {
 		return "", fmt.Errorf("error getting unstaged diff: %v", err)
 	}
 
-	// Combine both outputs
-	combinedDiff := string(stagedOutput) + string(unstagedOutput)
+	// Combine both outputs, binary files are summarized like untracked ones
+	combinedDiff := describeBinaryChanges(string(stagedOutput), readIndexFile, nil) + describeBinaryChanges(string(unstagedOutput), readWorktreeFile, worktreeZipEntries)
 
-	// Get untracked files
-	untrackedCmd := exec.Command("git", "ls-files", "--others", "--exclude-standard")
+	// Get untracked files, relative to the repository root like the paths in the diffs
+	untrackedCmd := exec.Command("git", "ls-files", "--others", "--exclude-standard", "--full-name", ":/")
 	untrackedOutput, err := untrackedCmd.Output()
 	if err != nil {
 		return "", fmt.Errorf("error getting untracked files: %v", err)
 	}
 
-	// If there are untracked files, add them to the diff summary
+	// If there are untracked files, add their content (or a summary for binary files)
 	if len(untrackedOutput) > 0 {
 		untrackedFiles := strings.Split(strings.TrimSpace(string(untrackedOutput)), "\n")
-		combinedDiff += "\n\nUntracked files:\n"
-		for _, file := range untrackedFiles {
-			combinedDiff += "  " + file + "\n"
-		}
+		combinedDiff += describeNewFiles(untrackedFiles)
 	}
 
 	return combinedDiff, nil
 }
 
+// HasStagedChanges reports whether the index differs from HEAD
+func HasStagedChanges() bool {
+	cmd := exec.Command("git", "diff", "--staged", "--quiet")
+	return cmd.Run() != nil
+}
+
+// GetStagedDiff returns the diff of the changes in the index only
+func GetStagedDiff() (string, error) {
+	stagedCmd := exec.Command("git", "diff", "--staged")
+	stagedOutput, err := stagedCmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("error getting staged diff: %v", err)
+	}
+	return describeBinaryChanges(string(stagedOutput), readIndexFile, nil), nil
+}
+
+// GetStagedDiffAgainst returns the diff of the index against base
+func GetStagedDiffAgainst(base string) (string, error) {
+	output, err := exec.Command("git", "diff", "--staged", base).Output()
+	if err != nil {
+		return "", fmt.Errorf("error getting staged diff against %s: %v", base, err)
+	}
+	return describeBinaryChanges(string(output), readIndexFile, nil), nil
+}
+
+// HasCommits reports whether HEAD points at a commit
+func HasCommits() bool {
+	return exec.Command("git", "rev-parse", "--verify", "--quiet", "HEAD").Run() == nil
+}
+
+// GetAmendBase returns what HEAD is compared against when amending it: its
+// parent, or the empty tree for a root commit
+func GetAmendBase() (string, error) {
+	if exec.Command("git", "rev-parse", "--verify", "--quiet", "HEAD~1").Run() == nil {
+		return "HEAD~1", nil
+	}
+
+	cmd := exec.Command("git", "hash-object", "-t", "tree", "--stdin")
+	cmd.Stdin = strings.NewReader("")
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("error getting empty tree: %v", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// GetPushedUpstream returns the upstream branch when HEAD is already
+// contained in it, or an empty string when HEAD is unpublished or there is no upstream
+func GetPushedUpstream() string {
+	output, err := exec.Command("git", "rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{upstream}").Output()
+	if err != nil {
+		return ""
+	}
+	upstream := strings.TrimSpace(string(output))
+
+	if exec.Command("git", "merge-base", "--is-ancestor", "HEAD", upstream).Run() != nil {
+		return ""
+	}
+	return upstream
+}
+
+// GetRepoRoot returns the top-level directory of the working tree
+func GetRepoRoot() (string, error) {
+	output, err := exec.Command("git", "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return "", fmt.Errorf("error getting repository root: %v", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// GetChangedPaths returns the paths of all staged, unstaged and untracked
+// changes, relative to the repository root
+func GetChangedPaths() ([]string, error) {
+	args := []string{"diff", "--name-only", "HEAD"}
+	if !HasCommits() {
+		args = []string{"diff", "--name-only", "--staged"}
+	}
+	output, err := exec.Command("git", args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("error getting changed paths: %v", err)
+	}
+
+	untrackedOutput, err := exec.Command("git", "ls-files", "--others", "--exclude-standard", "--full-name", ":/").Output()
+	if err != nil {
+		return nil, fmt.Errorf("error getting untracked files: %v", err)
+	}
+
+	return splitLines(string(output) + string(untrackedOutput)), nil
+}
+
+// GetStagedPaths returns the paths staged relative to base, or to HEAD when
+// base is empty, relative to the repository root
+func GetStagedPaths(base string) ([]string, error) {
+	args := []string{"diff", "--name-only", "--staged"}
+	if base != "" {
+		args = append(args, base)
+	}
+	output, err := exec.Command("git", args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("error getting staged paths: %v", err)
+	}
+	return splitLines(string(output)), nil
+}
+
+// splitLines splits git output with one entry per line, dropping blank lines
+func splitLines(output string) []string {
+	var paths []string
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			paths = append(paths, line)
+		}
+	}
+	return paths
+}
+
+// GetIdentity returns the committer as "Name <email>", as git would record it
+func GetIdentity() (string, error) {
+	output, err := exec.Command("git", "var", "GIT_COMMITTER_IDENT").Output()
+	if err != nil {
+		return "", fmt.Errorf("error getting committer identity, set user.name and user.email: %v", err)
+	}
+
+	// Drop the timestamp that follows the email
+	identity := strings.TrimSpace(string(output))
+	if end := strings.LastIndex(identity, ">"); end != -1 {
+		identity = identity[:end+1]
+	}
+	return identity, nil
+}
+
+// GetRecentAuthors returns the distinct authors and co-authors of the last
+// limit commits as "Name <email>", most recent first, without the committer
+func GetRecentAuthors(limit int) ([]string, error) {
+	output, err := exec.Command("git", "log", "-n", fmt.Sprint(limit), "--format=%aN <%aE>%n%(trailers:key=Co-authored-by,valueonly)").Output()
+	if err != nil {
+		return nil, fmt.Errorf("error getting recent authors: %v", err)
+	}
+
+	self, _ := GetIdentity()
+	seen := map[string]bool{strings.ToLower(self): true}
+	var authors []string
+	for _, author := range splitLines(string(output)) {
+		if key := strings.ToLower(author); !seen[key] {
+			seen[key] = true
+			authors = append(authors, author)
+		}
+	}
+	return authors, nil
+}
+
 func GetCurrentBranch() (string, error) {
 	cmd := exec.Command("git", "rev-parse", "--abbrev-ref", "HEAD")
 	output, err := cmd.Output()
@@ -91,8 +238,18 @@ func GetCurrentBranch() (string, error) {
 }
 
 func GetLastCommitMetadata() (string, error) {
-	// Get the last commit's metadata using git log
-	cmd := exec.Command("git", "log", "-1", "--pretty=format:%h|%an|%ad|%s")
+	return getCommitMetadata()
+}
+
+// GetParentCommitMetadata describes the commit before HEAD, in the same format as GetLastCommitMetadata
+func GetParentCommitMetadata() (string, error) {
+	return getCommitMetadata("HEAD~1")
+}
+
+func getCommitMetadata(revs ...string) (string, error) {
+	// Get the commit's metadata using git log
+	args := append([]string{"log", "-1", "--pretty=format:%h|%an|%ad|%s"}, revs...)
+	cmd := exec.Command("git", args...)
 	output, err := cmd.Output()
 	if err != nil {
 		// If there's no previous commit, return empty string
@@ -213,16 +370,11 @@ func GetFileDiff(filename string) (string, error) {
 	statusLine := strings.TrimSpace(string(statusOutput))
 
 	// Combine outputs
-	combinedDiff := string(stagedOutput) + string(unstagedOutput)
+	combinedDiff := describeBinaryChanges(string(stagedOutput), readIndexFile, nil) + describeBinaryChanges(string(unstagedOutput), readWorktreeFile, worktreeZipEntries)
 
-	// If it's an untracked file, show that it's new
+	// If it's an untracked file, show its content (or a summary for binary files)
 	if len(statusLine) > 0 && statusLine[0] == '?' {
-		combinedDiff += fmt.Sprintf("\nNew file: %s", filename)
-
-		// Try to show the content of new file (if it's text and not too large)
-		if fileContent, err := os.ReadFile(filename); err == nil && len(fileContent) < 2000 {
-			combinedDiff += fmt.Sprintf("\nFile content:\n%s", string(fileContent))
-		}
+		combinedDiff += describeNewFiles([]string{filename})
 	}
 
 	return combinedDiff, nil
@@ -245,3 +397,74 @@ func HandleLastCommit() {
 	fmt.Println("=======================")
 	fmt.Println(lastCommitInfo)
 }
+
+// GetDiffStat returns the diffstat of all changes to the given paths, or of
+// every change when no paths are given. Untracked files are listed as new files.
+func GetDiffStat(paths ...string) (string, error) {
+	args := append([]string{"diff", "HEAD", "--stat", "--"}, paths...)
+	output, err := exec.Command("git", args...).Output()
+	if err != nil {
+		// Without a HEAD commit only staged changes can be compared
+		args = append([]string{"diff", "--staged", "--stat", "--"}, paths...)
+		output, err = exec.Command("git", args...).Output()
+		if err != nil {
+			return "", fmt.Errorf("error getting diffstat: %v", err)
+		}
+	}
+	// Keep the "N files changed" summary line for the end
+	stat := strings.TrimRight(string(output), "\n")
+	summary := ""
+	if stat != "" {
+		idx := strings.LastIndex(stat, "\n")
+		summary = stat[idx+1:]
+		stat = stat[:idx+1]
+	}
+
+	// Add untracked files, which git diff does not know about
+	args = append([]string{"ls-files", "--others", "--exclude-standard", "--"}, paths...)
+	untrackedOutput, err := exec.Command("git", args...).Output()
+	if err != nil {
+		return "", fmt.Errorf("error getting untracked files: %v", err)
+	}
+	for _, file := range strings.Split(strings.TrimSpace(string(untrackedOutput)), "\n") {
+		if file != "" {
+			stat += fmt.Sprintf(" %s | new file\n", file)
+		}
+	}
+	if summary != "" {
+		stat += summary + "\n"
+	}
+
+	return stat, nil
+}
+
+// GetStagedDiffStat returns the diffstat of the index against base, or
+// against HEAD when base is empty
+func GetStagedDiffStat(base string) (string, error) {
+	args := []string{"diff", "--staged", "--stat"}
+	if base != "" {
+		args = append(args, base)
+	}
+	output, err := exec.Command("git", args...).Output()
+	if err != nil {
+		return "", fmt.Errorf("error getting diffstat: %v", err)
+	}
+	return string(output), nil
+}
+
+// GetEditor returns the editor git uses for commit messages, honoring
+// $GIT_EDITOR, core.editor, $VISUAL and $EDITOR
+func GetEditor() string {
+	if output, err := exec.Command("git", "var", "GIT_EDITOR").Output(); err == nil {
+		if editor := strings.TrimSpace(string(output)); editor != "" {
+			return editor
+		}
+	}
+
+	for _, name := range []string{"GIT_EDITOR", "VISUAL", "EDITOR"} {
+		if editor := os.Getenv(name); editor != "" {
+			return editor
+		}
+	}
+	return "vi"
+}
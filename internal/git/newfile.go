@@ -0,0 +1,287 @@
+package git
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/gif"  // Register GIF for image.DecodeConfig
+	_ "image/jpeg" // Register JPEG for image.DecodeConfig
+	_ "image/png"  // Register PNG for image.DecodeConfig
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	// maxNewFileBytes is how much of a new text file is included in a diff
+	maxNewFileBytes = 16 * 1024
+	// sniffBytes is how much of a file is checked for NUL bytes, as git does
+	sniffBytes = 8000
+	// maxArchiveBytes is the size above which the entries of an archive are not counted
+	maxArchiveBytes = 256 * 1024 * 1024
+)
+
+// describeNewFiles renders untracked files the way GetGitDiff and
+// GetFileDiff include them, each after a blank line. filenames are relative
+// to the repository root, whatever the current directory. Their
+// gitattributes are looked up with a single git call.
+func describeNewFiles(filenames []string) string {
+	root, err := GetRepoRoot()
+	if err != nil {
+		root = "."
+	}
+	binary := binaryByAttributes(root, filenames)
+
+	var out strings.Builder
+	for _, filename := range filenames {
+		out.WriteString("\n")
+		out.WriteString(describeNewFile(root, filename, binary[filename]))
+	}
+	return out.String()
+}
+
+// describeNewFile renders an untracked file: a "New file:" header, followed
+// by the content of text files or a type and size summary of binary files.
+// filename is relative to root. binaryAttr is set when gitattributes mark
+// the file as binary.
+func describeNewFile(root, filename string, binaryAttr bool) string {
+	var out strings.Builder
+	out.WriteString(fmt.Sprintf("New file: %s\n", filename))
+
+	path := filepath.Join(root, filepath.FromSlash(filename))
+	info, err := os.Stat(path)
+	if err != nil {
+		return out.String()
+	}
+	if info.Size() == 0 {
+		out.WriteString("Empty file\n")
+		return out.String()
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return out.String()
+	}
+	defer file.Close()
+
+	head := make([]byte, maxNewFileBytes)
+	n, _ := io.ReadFull(file, head)
+	head = head[:n]
+
+	if binaryAttr || hasNUL(head) {
+		countEntries := func() (int, error) { return zipEntries(path) }
+		out.WriteString(fmt.Sprintf("Binary file: %s\n", summarizeBinary(filename, head, info.Size(), countEntries)))
+		return out.String()
+	}
+
+	content := string(head)
+	if info.Size() > int64(len(head)) {
+		// Cut at the last complete line so no character is split
+		if idx := strings.LastIndex(content, "\n"); idx != -1 {
+			content = content[:idx+1]
+		}
+		out.WriteString(fmt.Sprintf("Size: %s, content truncated to the first %d lines\n", formatSize(info.Size()), strings.Count(content, "\n")))
+	}
+	out.WriteString("File content:\n")
+	out.WriteString(content)
+	if !strings.HasSuffix(content, "\n") {
+		out.WriteString("\n")
+	}
+
+	return out.String()
+}
+
+// binaryByAttributes returns the files, relative to root, gitattributes mark
+// as binary, with the binary macro or with -diff
+func binaryByAttributes(root string, filenames []string) map[string]bool {
+	binary := make(map[string]bool)
+	if len(filenames) == 0 {
+		return binary
+	}
+
+	cmd := exec.Command("git", "check-attr", "--stdin", "-z", "binary", "diff")
+	cmd.Dir = root
+	cmd.Stdin = strings.NewReader(strings.Join(filenames, "\x00") + "\x00")
+	output, err := cmd.Output()
+	if err != nil {
+		return binary
+	}
+
+	// Records are "path NUL attribute NUL value NUL"
+	fields := strings.Split(string(output), "\x00")
+	for i := 0; i+2 < len(fields); i += 3 {
+		path, attribute, value := fields[i], fields[i+1], fields[i+2]
+		if (attribute == "binary" && value == "set") || (attribute == "diff" && value == "unset") {
+			binary[path] = true
+		}
+	}
+	return binary
+}
+
+// hasNUL reports whether the first bytes of a file contain a NUL byte, which
+// is how git tells binary files from text
+func hasNUL(head []byte) bool {
+	if len(head) > sniffBytes {
+		head = head[:sniffBytes]
+	}
+	return bytes.IndexByte(head, 0) != -1
+}
+
+// describeBinaryChanges replaces the "Binary files ... differ" lines git
+// writes into diff with a summary of the new version of each file, the same
+// one untracked binary files get. read returns the first limit bytes of the
+// new version of path, relative to the repository root, and its size.
+// countEntries counts the entries of an archive at path, nil to not count
+// them. Deleted files keep git's line.
+func describeBinaryChanges(diff string, read func(path string, limit int) ([]byte, int64, error), countEntries func(path string) (int, error)) string {
+	if !strings.Contains(diff, "\nBinary files ") {
+		return diff
+	}
+
+	lines := strings.SplitAfter(diff, "\n")
+	for i, line := range lines {
+		text := strings.TrimSuffix(line, "\n")
+		if !strings.HasPrefix(text, "Binary files ") || !strings.HasSuffix(text, " differ") {
+			continue
+		}
+		files := strings.TrimSuffix(strings.TrimPrefix(text, "Binary files "), " differ")
+		idx := strings.LastIndex(files, " and b/")
+		if idx == -1 {
+			continue
+		}
+		path := files[idx+len(" and b/"):]
+
+		head, size, err := read(path, maxNewFileBytes)
+		if err != nil {
+			continue
+		}
+		var count func() (int, error)
+		if countEntries != nil {
+			count = func() (int, error) { return countEntries(path) }
+		}
+		lines[i] = fmt.Sprintf("Binary file: %s\n", summarizeBinary(path, head, size, count))
+	}
+	return strings.Join(lines, "")
+}
+
+// readIndexFile returns the first limit bytes and the size of the staged
+// version of path
+func readIndexFile(path string, limit int) ([]byte, int64, error) {
+	object := ":" + path
+	sizeOutput, err := exec.Command("git", "cat-file", "-s", object).Output()
+	if err != nil {
+		return nil, 0, fmt.Errorf("error getting size of %s: %v", object, err)
+	}
+	size, err := strconv.ParseInt(strings.TrimSpace(string(sizeOutput)), 10, 64)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error getting size of %s: %v", object, err)
+	}
+
+	cmd := exec.Command("git", "cat-file", "blob", object)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, 0, fmt.Errorf("error reading %s: %v", object, err)
+	}
+	content, err := io.ReadAll(io.LimitReader(stdout, int64(limit)))
+	// Stop git when only the start of a large file was needed
+	cmd.Process.Kill()
+	cmd.Wait()
+	if err != nil {
+		return nil, 0, fmt.Errorf("error reading %s: %v", object, err)
+	}
+	return content, size, nil
+}
+
+// readWorktreeFile is readIndexFile for the working tree version of path
+func readWorktreeFile(path string, limit int) ([]byte, int64, error) {
+	root, err := GetRepoRoot()
+	if err != nil {
+		return nil, 0, err
+	}
+	file, err := os.Open(filepath.Join(root, filepath.FromSlash(path)))
+	if err != nil {
+		return nil, 0, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, 0, err
+	}
+	content, err := io.ReadAll(io.LimitReader(file, int64(limit)))
+	if err != nil {
+		return nil, 0, err
+	}
+	return content, info.Size(), nil
+}
+
+// worktreeZipEntries counts the entries of the working tree version of the
+// archive at path, relative to the repository root
+func worktreeZipEntries(path string) (int, error) {
+	root, err := GetRepoRoot()
+	if err != nil {
+		return 0, err
+	}
+	return zipEntries(filepath.Join(root, filepath.FromSlash(path)))
+}
+
+// zipEntries counts the entries of the zip archive at path. Only its central
+// directory is read, not the whole file.
+func zipEntries(path string) (int, error) {
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return 0, err
+	}
+	defer archive.Close()
+	return len(archive.File), nil
+}
+
+// summarizeBinary describes a binary file by its type and size, plus the
+// dimensions of images and the entry count of zip archives up to
+// maxArchiveBytes. countEntries is only called for those archives, and may be
+// nil when the entries cannot be counted cheaply.
+func summarizeBinary(filename string, head []byte, size int64, countEntries func() (int, error)) string {
+	contentType := mime.TypeByExtension(filepath.Ext(filename))
+	if contentType == "" {
+		contentType = http.DetectContentType(head)
+	}
+	contentType, _, _ = strings.Cut(contentType, ";")
+
+	parts := []string{contentType, formatSize(size)}
+
+	if strings.HasPrefix(contentType, "image/") {
+		if config, _, err := image.DecodeConfig(bytes.NewReader(head)); err == nil {
+			parts = append(parts, fmt.Sprintf("%dx%d", config.Width, config.Height))
+		}
+	}
+
+	isArchive := contentType == "application/zip" || strings.HasSuffix(filename, ".jar")
+	if isArchive && countEntries != nil && size <= maxArchiveBytes {
+		if entries, err := countEntries(); err == nil {
+			parts = append(parts, fmt.Sprintf("archive with %d entries", entries))
+		}
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// formatSize formats a byte count for humans
+func formatSize(size int64) string {
+	switch {
+	case size >= 1024*1024:
+		return fmt.Sprintf("%.1f MB", float64(size)/(1024*1024))
+	case size >= 1024:
+		return fmt.Sprintf("%.1f KB", float64(size)/1024)
+	default:
+		return fmt.Sprintf("%d bytes", size)
+	}
+}
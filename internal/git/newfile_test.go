@@ -0,0 +1,185 @@
+package git
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDescribeBinaryChanges(t *testing.T) {
+	read := func(path string, limit int) ([]byte, int64, error) {
+		if path == "missing.bin" {
+			return nil, 0, fmt.Errorf("not staged")
+		}
+		return []byte{0, 1, 2}, 2048, nil
+	}
+
+	tests := []struct {
+		name string
+		diff string
+		want string
+	}{
+		{
+			name: "new file",
+			diff: "diff --git a/logo.bin b/logo.bin\nnew file mode 100644\nindex 0000000..1111111\nBinary files /dev/null and b/logo.bin differ\n",
+			want: "diff --git a/logo.bin b/logo.bin\nnew file mode 100644\nindex 0000000..1111111\nBinary file: application/octet-stream, 2.0 KB\n",
+		},
+		{
+			name: "modified file with a space",
+			diff: "diff --git a/my file.bin b/my file.bin\nBinary files a/my file.bin and b/my file.bin differ\n",
+			want: "diff --git a/my file.bin b/my file.bin\nBinary file: application/octet-stream, 2.0 KB\n",
+		},
+		{
+			name: "deleted file",
+			diff: "diff --git a/old.bin b/old.bin\nBinary files a/old.bin and /dev/null differ\n",
+			want: "diff --git a/old.bin b/old.bin\nBinary files a/old.bin and /dev/null differ\n",
+		},
+		{
+			name: "unreadable file",
+			diff: "diff --git a/missing.bin b/missing.bin\nBinary files /dev/null and b/missing.bin differ\n",
+			want: "diff --git a/missing.bin b/missing.bin\nBinary files /dev/null and b/missing.bin differ\n",
+		},
+		{
+			name: "text diff",
+			diff: "diff --git a/a.txt b/a.txt\n@@ -1 +1 @@\n-Binary files a and b differ\n+x\n",
+			want: "diff --git a/a.txt b/a.txt\n@@ -1 +1 @@\n-Binary files a and b differ\n+x\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := describeBinaryChanges(tt.diff, read, nil); got != tt.want {
+				t.Errorf("describeBinaryChanges =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+// newTestRepo creates a repository with one commit in a temporary directory
+// and makes it the current directory for the rest of the test
+func newTestRepo(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"config", "user.name", "Test"},
+		{"config", "user.email", "test@example.com"},
+		{"commit", "-q", "--allow-empty", "-m", "init"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = root
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, output)
+		}
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	if err := os.Chdir(root); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func writeTestFile(t *testing.T, path string, content []byte) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetGitDiffUntrackedFromSubdirectory(t *testing.T) {
+	root := newTestRepo(t)
+	writeTestFile(t, filepath.Join(root, ".gitattributes"), []byte("*.dat binary\n"))
+	writeTestFile(t, filepath.Join(root, "web", "gen", "x.pb.go"), []byte("package gen\n"))
+	writeTestFile(t, filepath.Join(root, "web", "blob.dat"), []byte("plain text"))
+	writeTestFile(t, filepath.Join(root, "cli", "main.go"), []byte("package main\n"))
+	if err := os.Chdir(filepath.Join(root, "web")); err != nil {
+		t.Fatal(err)
+	}
+
+	diff, err := GetGitDiff()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"New file: web/gen/x.pb.go\nFile content:\npackage gen\n",
+		"New file: web/blob.dat\nBinary file: ",
+		"New file: cli/main.go\nFile content:\npackage main\n",
+	} {
+		if !strings.Contains(diff, want) {
+			t.Errorf("diff is missing %q:\n%s", want, diff)
+		}
+	}
+}
+
+// writeTestZip writes a zip archive with the given number of entries
+func writeTestZip(t *testing.T, path string, entries int) {
+	t.Helper()
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for i := 0; i < entries; i++ {
+		if _, err := archive.Create(fmt.Sprintf("file%d.txt", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, path, buf.Bytes())
+}
+
+func TestSummarizeBinaryArchives(t *testing.T) {
+	head := []byte("PK\x03\x04")
+	noCount := func() (int, error) {
+		t.Error("entries of an archive over the size limit were counted")
+		return 0, nil
+	}
+
+	if got := summarizeBinary("big.zip", head, maxArchiveBytes+1, noCount); got != "application/zip, 256.0 MB" {
+		t.Errorf("summary of a large archive = %q", got)
+	}
+	if got := summarizeBinary("staged.jar", head, 2048, nil); strings.Contains(got, "entries") {
+		t.Errorf("summary without an entry counter = %q", got)
+	}
+
+	path := filepath.Join(t.TempDir(), "lib.jar")
+	writeTestZip(t, path, 3)
+	if entries, err := zipEntries(path); err != nil || entries != 3 {
+		t.Errorf("zipEntries = %d, %v, want 3 entries", entries, err)
+	}
+}
+
+func TestGetGitDiffArchives(t *testing.T) {
+	root := newTestRepo(t)
+	writeTestZip(t, filepath.Join(root, "staged.zip"), 2)
+	writeTestZip(t, filepath.Join(root, "untracked.zip"), 3)
+	if output, err := exec.Command("git", "add", "staged.zip").CombinedOutput(); err != nil {
+		t.Fatalf("git add: %v\n%s", err, output)
+	}
+
+	diff, err := GetGitDiff()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The index blob is not read to count its entries, the untracked file is
+	if !strings.Contains(diff, "Binary file: application/zip, 246 bytes\n") {
+		t.Errorf("staged archive summary missing or counted:\n%s", diff)
+	}
+	if !strings.Contains(diff, "New file: untracked.zip\nBinary file: application/zip, 358 bytes, archive with 3 entries\n") {
+		t.Errorf("untracked archive summary missing:\n%s", diff)
+	}
+}
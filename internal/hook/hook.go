@@ -0,0 +1,143 @@
+package hook
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Hook names gg can install
+const (
+	PrepareCommitMsg = "prepare-commit-msg"
+	CommitMsg        = "commit-msg"
+)
+
+// marker identifies hook scripts written by gg, so they can be updated and removed safely
+const marker = "# gg-managed hook"
+
+// Supported lists the hooks gg can install
+var Supported = []string{PrepareCommitMsg, CommitMsg}
+
+// HandleInstall writes the named hook into the repository's hooks directory
+func HandleInstall(name string, force bool) {
+	if !isSupported(name) {
+		fmt.Printf("Error: Unsupported hook %q (supported: %s)\n", name, strings.Join(Supported, ", "))
+		os.Exit(1)
+	}
+
+	hooksDir, err := getHooksDir()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Never overwrite a hook somebody else wrote unless asked to
+	hookPath := filepath.Join(hooksDir, name)
+	if content, err := os.ReadFile(hookPath); err == nil && !strings.Contains(string(content), marker) && !force {
+		fmt.Printf("Error: %s already exists and was not installed by gg.\n", hookPath)
+		fmt.Println("Remove it first or run 'gg hook install --force' to replace it.")
+		os.Exit(1)
+	}
+
+	if err := os.MkdirAll(hooksDir, 0755); err != nil {
+		fmt.Printf("Error creating hooks directory: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := os.WriteFile(hookPath, []byte(hookScript(name)), 0755); err != nil {
+		fmt.Printf("Error writing hook: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Installed %s hook at %s\n", name, hookPath)
+	fmt.Println("Remove it again with 'gg hook uninstall'.")
+}
+
+// HandleUninstall removes the named hooks, or every hook gg installed when
+// no names are given. Hooks gg did not write are left alone.
+func HandleUninstall(names []string) {
+	for _, name := range names {
+		if !isSupported(name) {
+			fmt.Printf("Error: Unsupported hook %q (supported: %s)\n", name, strings.Join(Supported, ", "))
+			os.Exit(1)
+		}
+	}
+	if len(names) == 0 {
+		names = Supported
+	}
+
+	hooksDir, err := getHooksDir()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	removed := 0
+	for _, name := range names {
+		hookPath := filepath.Join(hooksDir, name)
+		content, err := os.ReadFile(hookPath)
+		if err != nil {
+			continue
+		}
+		if !strings.Contains(string(content), marker) {
+			fmt.Printf("Warning: %s was not installed by gg, leaving it in place.\n", hookPath)
+			continue
+		}
+
+		if err := os.Remove(hookPath); err != nil {
+			fmt.Printf("Error removing %s: %v\n", hookPath, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Removed %s hook\n", name)
+		removed++
+	}
+
+	if removed == 0 {
+		fmt.Println("No gg hooks installed.")
+	}
+}
+
+// hookScript returns the shell script for the named hook. It calls gg by the
+// path it was installed from, so commits from IDEs with a minimal PATH work,
+// and never fails the commit when gg itself is unavailable. Only the
+// commit-msg hook can reject a commit, when the message breaks the rules.
+func hookScript(name string) string {
+	ggPath := "gg"
+	if exePath, err := os.Executable(); err == nil {
+		ggPath = exePath
+	}
+
+	run := fmt.Sprintf(`"$gg_bin" hook run %s "$@" || true`, name)
+	if name == CommitMsg {
+		run = `exec "$gg_bin" lint-message "$1"`
+	}
+
+	return fmt.Sprintf(`#!/bin/sh
+%s: %s
+# Installed by 'gg hook install %s', remove with 'gg hook uninstall'.
+gg_bin='%s'
+[ -x "$gg_bin" ] || gg_bin=gg
+command -v "$gg_bin" >/dev/null 2>&1 || exit 0
+%s
+`, marker, name, name, strings.ReplaceAll(ggPath, "'", `'\''`), run)
+}
+
+// getHooksDir returns the hooks directory git uses, honoring core.hooksPath
+func getHooksDir() (string, error) {
+	output, err := exec.Command("git", "rev-parse", "--git-path", "hooks").Output()
+	if err != nil {
+		return "", fmt.Errorf("not a git repository")
+	}
+	return filepath.Abs(strings.TrimSpace(string(output)))
+}
+
+func isSupported(name string) bool {
+	for _, supported := range Supported {
+		if name == supported {
+			return true
+		}
+	}
+	return false
+}
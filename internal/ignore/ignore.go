@@ -0,0 +1,158 @@
+package ignore
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// pattern is a single compiled line of an ignore file
+type pattern struct {
+	regex   *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// Matcher matches slash-separated paths against patterns in gitignore syntax
+type Matcher struct {
+	patterns []pattern
+}
+
+// Load reads an ignore file. A missing file gives a matcher that matches nothing.
+func Load(path string) (*Matcher, error) {
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &Matcher{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %v", path, err)
+	}
+	return Parse(string(content)), nil
+}
+
+// Parse compiles ignore file content in gitignore syntax
+func Parse(content string) *Matcher {
+	matcher := &Matcher{}
+	for _, line := range strings.Split(content, "\n") {
+		if p, ok := compile(line); ok {
+			matcher.patterns = append(matcher.patterns, p)
+		}
+	}
+	return matcher
+}
+
+// Empty reports whether the matcher has no patterns
+func (m *Matcher) Empty() bool {
+	return m == nil || len(m.patterns) == 0
+}
+
+// Match reports whether path, relative to the ignore file's directory, is
+// ignored. As in git, a file inside an ignored directory cannot be
+// re-included by a negated pattern.
+func (m *Matcher) Match(path string) bool {
+	if m.Empty() {
+		return false
+	}
+
+	path = strings.Trim(path, "/")
+	parts := strings.Split(path, "/")
+	for i := 1; i < len(parts); i++ {
+		if m.matches(strings.Join(parts[:i], "/"), true) {
+			return true
+		}
+	}
+	return m.matches(path, false)
+}
+
+// matches applies every pattern to path, the last matching one wins
+func (m *Matcher) matches(path string, isDir bool) bool {
+	ignored := false
+	for _, p := range m.patterns {
+		if p.dirOnly && !isDir {
+			continue
+		}
+		if p.regex.MatchString(path) {
+			ignored = !p.negate
+		}
+	}
+	return ignored
+}
+
+// compile turns one line of an ignore file into a pattern
+func compile(line string) (pattern, bool) {
+	line = strings.TrimRight(line, "\r")
+
+	// Trailing spaces are ignored unless escaped
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\\ ") {
+		line = line[:len(line)-1]
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return pattern{}, false
+	}
+
+	var p pattern
+	if strings.HasPrefix(line, "!") {
+		p.negate = true
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return pattern{}, false
+	}
+
+	// Patterns with a slash (other than a trailing one) are relative to the
+	// ignore file, the others match at any depth
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	if !anchored {
+		expr.WriteString("(?:.*/)?")
+	}
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case strings.HasPrefix(line[i:], "**/"):
+			expr.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(line[i:], "**") && i+2 == len(line):
+			expr.WriteString(".*")
+			i++
+		case c == '*':
+			expr.WriteString("[^/]*")
+		case c == '?':
+			expr.WriteString("[^/]")
+		case c == '\\' && i+1 < len(line):
+			i++
+			expr.WriteString(regexp.QuoteMeta(string(line[i])))
+		case c == '[':
+			end := strings.IndexByte(line[i+1:], ']')
+			if end == -1 {
+				expr.WriteString(`\[`)
+				continue
+			}
+			class := line[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			expr.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	expr.WriteString("$")
+
+	regex, err := regexp.Compile(expr.String())
+	if err != nil {
+		return pattern{}, false
+	}
+	p.regex = regex
+	return p, true
+}
@@ -0,0 +1,66 @@
+package ignore
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns string
+		path     string
+		want     bool
+	}{
+		// Unanchored patterns match at any depth
+		{name: "name at root", patterns: "go.sum", path: "go.sum", want: true},
+		{name: "name in subdirectory", patterns: "go.sum", path: "tools/go.sum", want: true},
+		{name: "glob at any depth", patterns: "*.pb.go", path: "api/v1/user.pb.go", want: true},
+		{name: "glob does not cross slashes", patterns: "*.go", path: "main.go.txt", want: false},
+		{name: "question mark", patterns: "file?.txt", path: "file1.txt", want: true},
+		{name: "character class", patterns: "file[0-9].txt", path: "fileA.txt", want: false},
+		{name: "negated class", patterns: "file[!0-9].txt", path: "fileA.txt", want: true},
+		{name: "unanchored directory name", patterns: "node_modules", path: "web/node_modules/react/index.js", want: true},
+
+		// Anchored patterns are relative to the ignore file
+		{name: "leading slash anchors", patterns: "/vendor", path: "vendor/lib.go", want: true},
+		{name: "leading slash does not match deeper", patterns: "/vendor", path: "third_party/vendor/lib.go", want: false},
+		{name: "middle slash anchors", patterns: "docs/*.md", path: "docs/intro.md", want: true},
+		{name: "middle slash does not match deeper", patterns: "docs/*.md", path: "web/docs/intro.md", want: false},
+		{name: "star stays in one directory", patterns: "docs/*.md", path: "docs/api/intro.md", want: false},
+
+		// Double star
+		{name: "leading double star", patterns: "**/testdata", path: "pkg/a/testdata/x.json", want: true},
+		{name: "leading double star at root", patterns: "**/testdata", path: "testdata/x.json", want: true},
+		{name: "trailing double star", patterns: "build/**", path: "build/out/app.js", want: true},
+		{name: "trailing double star needs the directory", patterns: "build/**", path: "build", want: false},
+		{name: "middle double star", patterns: "a/**/b.txt", path: "a/x/y/b.txt", want: true},
+		{name: "middle double star matches no directory", patterns: "a/**/b.txt", path: "a/b.txt", want: true},
+
+		// Directory-only patterns
+		{name: "directory pattern matches contents", patterns: "dist/", path: "dist/app.js", want: true},
+		{name: "directory pattern skips files", patterns: "dist/", path: "src/dist", want: false},
+		{name: "anchored directory pattern", patterns: "/vendor/", path: "vendor/modules.txt", want: true},
+
+		// Negation, the last matching pattern wins
+		{name: "negation re-includes", patterns: "*.log\n!keep.log", path: "keep.log", want: false},
+		{name: "negation leaves others", patterns: "*.log\n!keep.log", path: "debug.log", want: true},
+		{name: "later pattern wins", patterns: "!keep.log\n*.log", path: "keep.log", want: true},
+		{name: "negation in excluded directory", patterns: "fixtures/\n!fixtures/README.md", path: "fixtures/README.md", want: true},
+		{name: "negation after excluded contents", patterns: "fixtures/*\n!fixtures/README.md", path: "fixtures/README.md", want: false},
+		{name: "excluded contents still cover subdirectories", patterns: "fixtures/*\n!fixtures/README.md", path: "fixtures/sub/README.md", want: true},
+
+		// Syntax
+		{name: "comment", patterns: "# go.sum", path: "# go.sum", want: false},
+		{name: "escaped hash", patterns: `\#notes`, path: "#notes", want: true},
+		{name: "escaped bang", patterns: `\!important`, path: "!important", want: true},
+		{name: "trailing spaces ignored", patterns: "go.sum  ", path: "go.sum", want: true},
+		{name: "CRLF line ending", patterns: "go.sum\r\n", path: "go.sum", want: true},
+		{name: "no patterns", patterns: "", path: "go.sum", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Parse(tt.patterns).Match(tt.path); got != tt.want {
+				t.Errorf("Parse(%q).Match(%q) = %v, want %v", tt.patterns, tt.path, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	defaultAnthropicURL = "https://api.anthropic.com"
+	anthropicVersion    = "2023-06-01"
+)
+
+// anthropicProvider talks to the Anthropic Messages API
+type anthropicProvider struct {
+	apiKey  string
+	baseURL string
+}
+
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type anthropicRequest struct {
+	Model       string             `json:"model"`
+	System      string             `json:"system,omitempty"`
+	Messages    []anthropicMessage `json:"messages"`
+	MaxTokens   int                `json:"max_tokens"`
+	Temperature *float32           `json:"temperature,omitempty"`
+	Stream      bool               `json:"stream,omitempty"`
+}
+
+// anthropicStreamEvent is the part of a server-sent event that carries text
+type anthropicStreamEvent struct {
+	Type  string `json:"type"`
+	Delta struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"delta"`
+}
+
+type anthropicResponse struct {
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+}
+
+func newAnthropic(apiKey, baseURL string) *anthropicProvider {
+	if baseURL == "" {
+		baseURL = defaultAnthropicURL
+	}
+	return &anthropicProvider{
+		apiKey:  apiKey,
+		baseURL: strings.TrimRight(baseURL, "/"),
+	}
+}
+
+func (p *anthropicProvider) Name() string {
+	return "anthropic"
+}
+
+func (p *anthropicProvider) DefaultModel() string {
+	return "claude-3-5-haiku-latest"
+}
+
+func (p *anthropicProvider) Complete(ctx context.Context, req Request) (string, error) {
+	body := p.messagesRequest(req, false)
+
+	var resp anthropicResponse
+	if err := postJSON(ctx, p.baseURL+"/v1/messages", p.headers(), body, &resp); err != nil {
+		return "", fmt.Errorf("anthropic messages error: %v", err)
+	}
+
+	var text strings.Builder
+	for _, block := range resp.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+
+	return text.String(), nil
+}
+
+func (p *anthropicProvider) Stream(ctx context.Context, req Request, onToken func(string)) (string, error) {
+	body := p.messagesRequest(req, true)
+
+	// Text arrives in content_block_delta server-sent events
+	var text strings.Builder
+	err := postStream(ctx, p.baseURL+"/v1/messages", p.headers(), body, func(line []byte) error {
+		data, ok := bytes.CutPrefix(line, []byte("data:"))
+		if !ok {
+			return nil
+		}
+		var event anthropicStreamEvent
+		if err := json.Unmarshal(bytes.TrimSpace(data), &event); err != nil {
+			return fmt.Errorf("error decoding stream: %v", err)
+		}
+		if event.Type == "content_block_delta" && event.Delta.Type == "text_delta" {
+			text.WriteString(event.Delta.Text)
+			onToken(event.Delta.Text)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("anthropic messages error: %v", err)
+	}
+
+	return text.String(), nil
+}
+
+func (p *anthropicProvider) messagesRequest(req Request, stream bool) anthropicRequest {
+	body := anthropicRequest{
+		Model:       modelOrDefault(p, req),
+		MaxTokens:   req.MaxTokens,
+		Temperature: req.Temperature,
+		Stream:      stream,
+	}
+
+	// Anthropic takes the system prompt as a separate field
+	var system []string
+	for _, msg := range req.Messages {
+		if msg.Role == RoleSystem {
+			system = append(system, msg.Content)
+			continue
+		}
+		body.Messages = append(body.Messages, anthropicMessage{Role: msg.Role, Content: msg.Content})
+	}
+	body.System = strings.Join(system, "\n\n")
+
+	return body
+}
+
+func (p *anthropicProvider) headers() map[string]string {
+	return map[string]string{
+		"x-api-key":         p.apiKey,
+		"anthropic-version": anthropicVersion,
+	}
+}
@@ -0,0 +1,101 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const defaultOllamaURL = "http://localhost:11434"
+
+// ollamaProvider talks to a local or self-hosted Ollama server
+type ollamaProvider struct {
+	baseURL string
+}
+
+type ollamaMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type ollamaOptions struct {
+	NumPredict  int      `json:"num_predict,omitempty"`
+	Temperature *float32 `json:"temperature,omitempty"`
+}
+
+type ollamaChatRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Stream   bool            `json:"stream"`
+	Options  ollamaOptions   `json:"options"`
+}
+
+type ollamaChatResponse struct {
+	Message ollamaMessage `json:"message"`
+	Done    bool          `json:"done"`
+}
+
+func newOllama(baseURL string) *ollamaProvider {
+	if baseURL == "" {
+		baseURL = defaultOllamaURL
+	}
+	return &ollamaProvider{baseURL: strings.TrimRight(baseURL, "/")}
+}
+
+func (p *ollamaProvider) Name() string {
+	return "ollama"
+}
+
+func (p *ollamaProvider) DefaultModel() string {
+	return "llama3.1"
+}
+
+func (p *ollamaProvider) Complete(ctx context.Context, req Request) (string, error) {
+	body := p.chatRequest(req, false)
+
+	var resp ollamaChatResponse
+	if err := postJSON(ctx, p.baseURL+"/api/chat", nil, body, &resp); err != nil {
+		return "", fmt.Errorf("ollama chat error: %v", err)
+	}
+
+	return resp.Message.Content, nil
+}
+
+func (p *ollamaProvider) Stream(ctx context.Context, req Request, onToken func(string)) (string, error) {
+	body := p.chatRequest(req, true)
+
+	// Ollama streams one JSON object per line
+	var text strings.Builder
+	err := postStream(ctx, p.baseURL+"/api/chat", nil, body, func(line []byte) error {
+		var chunk ollamaChatResponse
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return fmt.Errorf("error decoding stream: %v", err)
+		}
+		if chunk.Message.Content != "" {
+			text.WriteString(chunk.Message.Content)
+			onToken(chunk.Message.Content)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("ollama chat error: %v", err)
+	}
+
+	return text.String(), nil
+}
+
+func (p *ollamaProvider) chatRequest(req Request, stream bool) ollamaChatRequest {
+	body := ollamaChatRequest{
+		Model:  modelOrDefault(p, req),
+		Stream: stream,
+		Options: ollamaOptions{
+			NumPredict:  req.MaxTokens,
+			Temperature: req.Temperature,
+		},
+	}
+	for _, msg := range req.Messages {
+		body.Messages = append(body.Messages, ollamaMessage{Role: msg.Role, Content: msg.Content})
+	}
+	return body
+}
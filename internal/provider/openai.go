@@ -0,0 +1,165 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"sync"
+
+	openai "github.com/sashabaranov/go-openai"
+	"github.com/user/gitgud/internal/config"
+)
+
+// openAIProvider talks to the OpenAI API or any server exposing the same
+// chat completions endpoint (vLLM, LM Studio, llama.cpp, ...)
+type openAIProvider struct {
+	name    string
+	baseURL string
+
+	mu     sync.Mutex
+	apiKey string
+	client *openai.Client
+
+	// rotateKey returns a replacement for a key the API rejected.
+	// When nil, authentication errors are returned as is.
+	rotateKey func(rejected string) (string, error)
+}
+
+func newOpenAI(name, apiKey, baseURL string) *openAIProvider {
+	p := &openAIProvider{name: name, baseURL: baseURL}
+	p.setKey(apiKey)
+	return p
+}
+
+// setKey replaces the API key and the client using it. Callers must hold mu
+// unless the provider is not shared yet.
+func (p *openAIProvider) setKey(apiKey string) {
+	clientConfig := openai.DefaultConfig(apiKey)
+	if p.baseURL != "" {
+		clientConfig.BaseURL = p.baseURL
+	}
+
+	p.apiKey = apiKey
+	p.client = openai.NewClientWithConfig(clientConfig)
+}
+
+func (p *openAIProvider) Name() string {
+	return p.name
+}
+
+func (p *openAIProvider) DefaultModel() string {
+	return openai.GPT4Dot1Nano
+}
+
+func (p *openAIProvider) Complete(ctx context.Context, req Request) (string, error) {
+	var resp openai.ChatCompletionResponse
+	err := p.withClient(func(client *openai.Client) error {
+		var err error
+		resp, err = client.CreateChatCompletion(ctx, p.chatRequest(req))
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("chat completion error: %v", err)
+	}
+
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("chat completion returned no choices")
+	}
+
+	return resp.Choices[0].Message.Content, nil
+}
+
+func (p *openAIProvider) Stream(ctx context.Context, req Request, onToken func(string)) (string, error) {
+	var text strings.Builder
+	err := p.withClient(func(client *openai.Client) error {
+		stream, err := client.CreateChatCompletionStream(ctx, p.chatRequest(req))
+		if err != nil {
+			return err
+		}
+		defer stream.Close()
+
+		for {
+			chunk, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != "" {
+				text.WriteString(chunk.Choices[0].Delta.Content)
+				onToken(chunk.Choices[0].Delta.Content)
+			}
+		}
+	})
+	if err != nil {
+		return "", fmt.Errorf("chat completion error: %v", err)
+	}
+
+	return text.String(), nil
+}
+
+func (p *openAIProvider) chatRequest(req Request) openai.ChatCompletionRequest {
+	messages := make([]openai.ChatCompletionMessage, len(req.Messages))
+	for i, msg := range req.Messages {
+		messages[i] = openai.ChatCompletionMessage{
+			Role:    msg.Role,
+			Content: msg.Content,
+		}
+	}
+
+	chatReq := openai.ChatCompletionRequest{
+		Model:     modelOrDefault(p, req),
+		Messages:  messages,
+		MaxTokens: req.MaxTokens,
+	}
+	if req.Temperature != nil {
+		chatReq.Temperature = *req.Temperature
+		// go-openai omits a zero temperature, which gives the server default
+		if chatReq.Temperature == 0 {
+			chatReq.Temperature = math.SmallestNonzeroFloat32
+		}
+	}
+	return chatReq
+}
+
+// withClient runs call with the current client and, when the API rejects
+// the key, once more with the next available key
+func (p *openAIProvider) withClient(call func(client *openai.Client) error) error {
+	p.mu.Lock()
+	apiKey, client := p.apiKey, p.client
+	p.mu.Unlock()
+
+	err := call(client)
+	if err != nil && p.rotateKey != nil && config.IsUnauthorized(err) {
+		if client, err = p.replaceKey(apiKey); err != nil {
+			return err
+		}
+		err = call(client)
+	}
+	return err
+}
+
+// replaceKey swaps out a rejected key, unless a concurrent request already did
+func (p *openAIProvider) replaceKey(rejected string) (*openai.Client, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.apiKey != rejected {
+		return p.client, nil
+	}
+
+	apiKey, err := p.rotateKey(rejected)
+	if err != nil {
+		return nil, err
+	}
+	if apiKey == "" {
+		return nil, fmt.Errorf("OpenAI API key is required")
+	}
+
+	p.setKey(apiKey)
+	return p.client, nil
+}
@@ -0,0 +1,62 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOpenAITemperatureOnTheWire(t *testing.T) {
+	zero, warm := float32(0), float32(0.7)
+	tests := []struct {
+		name        string
+		temperature *float32
+		// want is the temperature sent, nil when the field must be left out
+		want *float64
+	}{
+		{name: "backend default", temperature: nil, want: nil},
+		{name: "zero", temperature: &zero, want: new(float64)},
+		{name: "non-zero", temperature: &warm, want: func() *float64 { v := 0.7; return &v }()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body map[string]any
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Errorf("decoding request: %v", err)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"feat: x"}}]}`))
+			}))
+			defer server.Close()
+
+			p := newOpenAI("openai-compatible", "key", server.URL)
+			_, err := p.Complete(context.Background(), Request{
+				Model:       "model",
+				Messages:    []Message{{Role: RoleUser, Content: "hi"}},
+				Temperature: tt.temperature,
+			})
+			if err != nil {
+				t.Fatalf("Complete: %v", err)
+			}
+
+			value, sent := body["temperature"]
+			if tt.want == nil {
+				if sent {
+					t.Errorf("temperature = %v, want it left out", value)
+				}
+				return
+			}
+			if !sent {
+				t.Fatalf("temperature was left out, want %v", *tt.want)
+			}
+			got, ok := value.(float64)
+			if !ok || got < *tt.want-1e-6 || got > *tt.want+1e-6 {
+				t.Errorf("temperature = %v, want %v", value, *tt.want)
+			}
+		})
+	}
+}
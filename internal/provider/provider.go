@@ -0,0 +1,110 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/user/gitgud/internal/config"
+)
+
+// Message roles shared by all providers
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// Message is a single chat message sent to a provider
+type Message struct {
+	Role    string
+	Content string
+}
+
+// Request describes a chat completion request independently of the backend
+type Request struct {
+	Model     string
+	Messages  []Message
+	MaxTokens int
+	// Temperature is left to the backend default when nil
+	Temperature *float32
+}
+
+// Provider is a language model backend that can complete a chat conversation
+type Provider interface {
+	// Name returns the configured provider name
+	Name() string
+	// DefaultModel returns the model used when the request does not set one
+	DefaultModel() string
+	// Complete sends the conversation and returns the generated text
+	Complete(ctx context.Context, req Request) (string, error)
+	// Stream sends the conversation, calls onToken for every piece of text as
+	// it arrives and returns the full generated text
+	Stream(ctx context.Context, req Request, onToken func(string)) (string, error)
+}
+
+// New creates the provider selected in the configuration. interactive tells
+// whether the user may be asked for a missing or rejected API key; when it is
+// not set, such keys are reported as errors.
+func New(cfg config.Config, interactive bool) (Provider, error) {
+	switch cfg.Provider {
+	case "", config.ProviderOpenAI:
+		apiKey, err := config.GetOpenAIAPIKey(interactive)
+		if err != nil {
+			return nil, err
+		}
+		if apiKey == "" {
+			return nil, fmt.Errorf("OpenAI API key is required")
+		}
+
+		// The key is not checked up front; when the API rejects it during
+		// generation, the next available key is looked up and the request retried
+		p := newOpenAI(config.ProviderOpenAI, apiKey, cfg.BaseURL)
+		p.rotateKey = func(rejected string) (string, error) {
+			config.MarkAPIKeyInvalid(rejected)
+			fmt.Println("The OpenAI API key was rejected, looking for another one...")
+			return config.GetOpenAIAPIKey(interactive)
+		}
+		return p, nil
+
+	case config.ProviderOpenAICompatible:
+		if cfg.BaseURL == "" {
+			return nil, fmt.Errorf("provider %q requires base_url to be set", cfg.Provider)
+		}
+		return newOpenAI(cfg.Provider, cfg.OpenAIAPIKey, cfg.BaseURL), nil
+
+	case config.ProviderLlamaCpp:
+		baseURL := cfg.BaseURL
+		if baseURL == "" {
+			baseURL = "http://localhost:8080/v1"
+		}
+		return newOpenAI(cfg.Provider, cfg.OpenAIAPIKey, baseURL), nil
+
+	case config.ProviderOllama:
+		return newOllama(cfg.BaseURL), nil
+
+	case config.ProviderAnthropic:
+		apiKey, err := config.GetAnthropicAPIKey()
+		if err != nil {
+			return nil, err
+		}
+		return newAnthropic(apiKey, cfg.BaseURL), nil
+
+	default:
+		return nil, fmt.Errorf("unknown provider %q (supported: %s, %s, %s, %s, %s)",
+			cfg.Provider,
+			config.ProviderOpenAI,
+			config.ProviderOpenAICompatible,
+			config.ProviderOllama,
+			config.ProviderLlamaCpp,
+			config.ProviderAnthropic,
+		)
+	}
+}
+
+// modelOrDefault returns the request model, falling back to the provider default
+func modelOrDefault(p Provider, req Request) string {
+	if req.Model != "" {
+		return req.Model
+	}
+	return p.DefaultModel()
+}
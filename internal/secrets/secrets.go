@@ -0,0 +1,248 @@
+package secrets
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Kinds of secrets the scanner reports
+const (
+	KindPrivateKey  = "private key"
+	KindAPIKey      = "API key"
+	KindToken       = "token"
+	KindCredential  = "credential"
+	KindHighEntropy = "high-entropy string"
+	KindEnvValue    = ".env value"
+)
+
+// Finding is a secret found in a diff
+type Finding struct {
+	// Path is the file the secret was found in, empty when unknown
+	Path string
+	Kind string
+	// Secret is the exact text that has to be redacted
+	Secret string
+}
+
+// tokenPatterns match well-known key and token formats
+var tokenPatterns = []struct {
+	kind    string
+	pattern *regexp.Regexp
+}{
+	{KindAPIKey, regexp.MustCompile(`sk-(?:ant-|proj-)?[A-Za-z0-9_-]{20,}`)},
+	{KindAPIKey, regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{KindAPIKey, regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+	{KindAPIKey, regexp.MustCompile(`\b[rs]k_live_[0-9A-Za-z]{20,}\b`)},
+	{KindToken, regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`)},
+	{KindToken, regexp.MustCompile(`\bgithub_pat_[A-Za-z0-9_]{40,}\b`)},
+	{KindToken, regexp.MustCompile(`\bglpat-[A-Za-z0-9_-]{20,}\b`)},
+	{KindToken, regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}\b`)},
+	{KindToken, regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}\b`)},
+}
+
+var (
+	// privateKeyBegin and privateKeyEnd delimit PEM private keys
+	privateKeyBegin = regexp.MustCompile(`-----BEGIN [A-Z0-9 ]*PRIVATE KEY( BLOCK)?-----`)
+	privateKeyEnd   = regexp.MustCompile(`-----END [A-Z0-9 ]*PRIVATE KEY( BLOCK)?-----`)
+	// credentialPattern matches assignments to variables that usually hold secrets
+	credentialPattern = regexp.MustCompile(`(?i)(?:api[_-]?key|secret|token|passw(?:or)?d|pwd|access[_-]?key|private[_-]?key|client[_-]?secret|auth(?:[_-]?token|[_-]?key|orization))[A-Za-z0-9_-]*["']?\s*[:=]\s*["']?([A-Za-z0-9+/=_.~@:-]{8,})`)
+	// quotedPattern matches long quoted strings, which are checked for high entropy
+	quotedPattern = regexp.MustCompile(`["'\x60]([A-Za-z0-9+/=_-]{32,})["'\x60]`)
+	// envLinePattern matches KEY=VALUE lines in .env files
+	envLinePattern = regexp.MustCompile(`^\s*(?:export\s+)?[A-Za-z_][A-Za-z0-9_]*\s*=\s*["']?([^"'\s#]+)`)
+	// referencePattern matches values that refer to a secret instead of holding it, e.g. process.env.TOKEN
+	referencePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)+$`)
+	// digitPattern matches any digit
+	digitPattern = regexp.MustCompile(`[0-9]`)
+	// placeholderPattern matches values that only stand in for a secret
+	placeholderPattern = regexp.MustCompile(`(?i)^(x{4,}|\*{4,}|<.*>|\$\{?.*|your[_-].*|changeme|example.*|placeholder|redacted.*|true|false|null|none)$`)
+)
+
+// ScanDiff finds secrets in a diff produced by the git package. File paths are
+// taken from "diff --git" and "New file:" lines, so added, removed and
+// untracked file contents are all scanned.
+func ScanDiff(diff string) []Finding {
+	var findings []Finding
+	seen := make(map[string]bool)
+
+	walkDiff(diff, func(line string, lineFindings []Finding) string {
+		for _, finding := range lineFindings {
+			key := finding.Path + "\x00" + finding.Secret
+			if !seen[key] {
+				seen[key] = true
+				findings = append(findings, finding)
+			}
+		}
+		return line
+	})
+
+	return findings
+}
+
+// RedactDiff replaces every secret ScanDiff would find with a marker naming
+// its kind. Only the lines a secret was found on are changed, so short values
+// from .env files are not replaced anywhere else in the diff.
+func RedactDiff(diff string) string {
+	return walkDiff(diff, func(line string, lineFindings []Finding) string {
+		// Replace longer secrets first so overlapping matches stay redacted
+		sort.SliceStable(lineFindings, func(i, j int) bool {
+			return len(lineFindings[i].Secret) > len(lineFindings[j].Secret)
+		})
+		for _, finding := range lineFindings {
+			line = strings.ReplaceAll(line, finding.Secret, fmt.Sprintf("[REDACTED %s]", finding.Kind))
+		}
+		return line
+	})
+}
+
+// walkDiff scans every line of diff and rebuilds it from what fn returns for
+// each line and the secrets found on it
+func walkDiff(diff string, fn func(line string, findings []Finding) string) string {
+	lines := strings.Split(diff, "\n")
+	currentPath := ""
+	inPrivateKey := false
+
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			currentPath = ""
+			if idx := strings.LastIndex(line, " b/"); idx != -1 {
+				currentPath = line[idx+3:]
+			}
+			inPrivateKey = false
+			continue
+		case strings.HasPrefix(line, "New file: "):
+			currentPath = strings.TrimPrefix(line, "New file: ")
+			inPrivateKey = false
+			continue
+		case strings.HasPrefix(line, "index "), strings.HasPrefix(line, "--- "), strings.HasPrefix(line, "+++ "), strings.HasPrefix(line, "@@"):
+			continue
+		}
+
+		// Drop the diff marker so the content is scanned as written
+		content := line
+		if strings.HasPrefix(content, "+") || strings.HasPrefix(content, "-") || strings.HasPrefix(content, " ") {
+			content = content[1:]
+		}
+
+		var findings []Finding
+		if privateKeyBegin.MatchString(content) {
+			inPrivateKey = true
+		}
+		if inPrivateKey {
+			// Every line of a private key is secret, including the base64 body
+			if body := strings.TrimSpace(content); body != "" && !privateKeyBegin.MatchString(body) && !privateKeyEnd.MatchString(body) {
+				findings = append(findings, Finding{Kind: KindPrivateKey, Secret: body})
+			}
+			if privateKeyEnd.MatchString(content) {
+				inPrivateKey = false
+			}
+		} else {
+			findings = scanLine(content, isEnvFile(currentPath))
+		}
+
+		if len(findings) == 0 {
+			continue
+		}
+		for j := range findings {
+			findings[j].Path = currentPath
+		}
+		lines[i] = fn(line, findings)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// scanLine finds secrets in a single line of file content
+func scanLine(line string, envFile bool) []Finding {
+	var findings []Finding
+
+	for _, token := range tokenPatterns {
+		for _, match := range token.pattern.FindAllString(line, -1) {
+			findings = append(findings, Finding{Kind: token.kind, Secret: match})
+		}
+	}
+
+	if envFile {
+		if match := envLinePattern.FindStringSubmatch(line); match != nil && !placeholderPattern.MatchString(match[1]) {
+			findings = append(findings, Finding{Kind: KindEnvValue, Secret: match[1]})
+		}
+	}
+
+	for _, match := range credentialPattern.FindAllStringSubmatch(line, -1) {
+		value := match[1]
+		if placeholderPattern.MatchString(value) || referencePattern.MatchString(value) {
+			continue
+		}
+		if entropy(value) >= 3.0 && (digitPattern.MatchString(value) || len(value) >= 12) {
+			findings = append(findings, Finding{Kind: KindCredential, Secret: value})
+		}
+	}
+
+	for _, match := range quotedPattern.FindAllStringSubmatch(line, -1) {
+		value := match[1]
+		if isChecksum(value) {
+			continue
+		}
+		if entropy(value) >= 4.0 {
+			findings = append(findings, Finding{Kind: KindHighEntropy, Secret: value})
+		}
+	}
+
+	return findings
+}
+
+// Mask shortens a secret for display without revealing it
+func Mask(secret string) string {
+	if len(secret) <= 12 {
+		return strings.Repeat("*", len(secret))
+	}
+	return secret[:4] + "..." + secret[len(secret)-2:]
+}
+
+// isEnvFile reports whether path is a dotenv file. Example and template files are not.
+func isEnvFile(filePath string) bool {
+	name := path.Base(filePath)
+	if name != ".env" && !strings.HasPrefix(name, ".env.") && !strings.HasSuffix(name, ".env") {
+		return false
+	}
+	for _, suffix := range []string{".example", ".sample", ".template", ".dist"} {
+		if strings.HasSuffix(name, suffix) {
+			return false
+		}
+	}
+	return true
+}
+
+// isChecksum reports whether value is a lockfile integrity hash rather than a secret
+func isChecksum(value string) bool {
+	for _, prefix := range []string{"sha1-", "sha256-", "sha384-", "sha512-"} {
+		if strings.HasPrefix(value, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// entropy returns the Shannon entropy of s in bits per character
+func entropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	counts := make(map[rune]int)
+	for _, r := range s {
+		counts[r]++
+	}
+
+	total := float64(len([]rune(s)))
+	result := 0.0
+	for _, count := range counts {
+		p := float64(count) / total
+		result -= p * math.Log2(p)
+	}
+	return result
+}
@@ -158,3 +158,163 @@ func SelectMultipleFilesWithArrows(files []string) ([]string, error) {
 
 	return selectedFiles, nil
 }
+
+// commitCandidate is a generated commit message shown in the candidate list
+type commitCandidate struct {
+	Subject string
+	Message string
+}
+
+// SelectCommitMessage lets the user pick one of several generated commit
+// messages and optionally edit its subject line before using it
+func SelectCommitMessage(messages []string) (string, error) {
+	if len(messages) == 0 {
+		return "", fmt.Errorf("no commit messages to choose from")
+	}
+
+	// Create options, showing the subject line with the full message below
+	choices := make([]commitCandidate, len(messages)+1)
+	for i, message := range messages {
+		subject, _, _ := strings.Cut(message, "\n")
+		choices[i] = commitCandidate{Subject: fmt.Sprintf("📝 %s", subject), Message: message}
+	}
+	choices[len(messages)] = commitCandidate{Subject: "❌ Exit"}
+
+	// Create the selection prompt using promptui
+	prompt := promptui.Select{
+		Label: "Choose a commit message",
+		Items: choices,
+		Templates: &promptui.SelectTemplates{
+			Label:    "{{ . }}:",
+			Active:   "▶ {{ .Subject | cyan }}",
+			Inactive: "  {{ .Subject }}",
+			Selected: "{{ .Subject | red | cyan }}",
+			Details: `
+{{ if .Message }}--------- Full message ----------
+{{ .Message }}{{ end }}`,
+		},
+		Size: 10,
+	}
+
+	selectedIndex, _, err := prompt.Run()
+	if err != nil {
+		if err == promptui.ErrInterrupt {
+			return "", fmt.Errorf("user chose to exit")
+		}
+		return "", fmt.Errorf("error running selection prompt: %v", err)
+	}
+	if selectedIndex == len(messages) {
+		return "", fmt.Errorf("user chose to exit")
+	}
+	message := messages[selectedIndex]
+
+	// Offer to edit the chosen message before using it
+	action := promptui.Select{
+		Label: "Use this message",
+		Items: []string{"✅ Use as is", "✏️  Edit subject line"},
+		Templates: &promptui.SelectTemplates{
+			Label:    "{{ . }}:",
+			Active:   "▶ {{ . | cyan }}",
+			Inactive: "  {{ . }}",
+			Selected: "{{ . | red | cyan }}",
+		},
+	}
+
+	actionIndex, _, err := action.Run()
+	if err != nil {
+		if err == promptui.ErrInterrupt {
+			return "", fmt.Errorf("user chose to exit")
+		}
+		return "", fmt.Errorf("error running selection prompt: %v", err)
+	}
+	if actionIndex == 0 {
+		return message, nil
+	}
+
+	subject, body, hasBody := strings.Cut(message, "\n")
+	edit := promptui.Prompt{
+		Label:     "Subject",
+		Default:   subject,
+		AllowEdit: true,
+	}
+	edited, err := edit.Run()
+	if err != nil {
+		if err == promptui.ErrInterrupt {
+			return "", fmt.Errorf("user chose to exit")
+		}
+		return "", fmt.Errorf("error running edit prompt: %v", err)
+	}
+
+	edited = strings.TrimSpace(edited)
+	if edited == "" {
+		edited = subject
+	}
+	if hasBody {
+		return edited + "\n" + body, nil
+	}
+	return edited, nil
+}
+
+// SelectCoAuthors lets the user pick any number of co-authors, one at a time,
+// from authors given as "Name <email>"
+func SelectCoAuthors(authors []string) ([]string, error) {
+	var selected []string
+	remaining := make([]string, len(authors))
+	copy(remaining, authors)
+
+	for {
+		if len(selected) > 0 {
+			fmt.Println("Co-authors selected so far:")
+			for _, author := range selected {
+				fmt.Printf("  👥 %s\n", author)
+			}
+			fmt.Println()
+		}
+
+		// Create options
+		choices := make([]string, len(remaining)+2)
+		choices[0] = "✅ Done"
+		choices[1] = "❌ Exit"
+		for i, author := range remaining {
+			choices[i+2] = fmt.Sprintf("👤 %s", author)
+		}
+
+		prompt := promptui.Select{
+			Label: "Add co-authors",
+			Items: choices,
+			Templates: &promptui.SelectTemplates{
+				Label:    "{{ . }}:",
+				Active:   "▶ {{ . | cyan }}",
+				Inactive: "  {{ . }}",
+				Selected: "{{ . | red | cyan }}",
+			},
+			Size: 15,
+			Searcher: func(input string, index int) bool {
+				return strings.Contains(strings.ToLower(choices[index]), strings.ToLower(input))
+			},
+		}
+
+		selectedIndex, _, err := prompt.Run()
+		if err != nil {
+			if err == promptui.ErrInterrupt {
+				return nil, fmt.Errorf("user chose to exit")
+			}
+			return nil, fmt.Errorf("error running selection prompt: %v", err)
+		}
+
+		switch selectedIndex {
+		case 0: // Done
+			return selected, nil
+		case 1: // Exit
+			return nil, fmt.Errorf("user chose to exit")
+		default: // Add an author
+			authorIndex := selectedIndex - 2
+			selected = append(selected, remaining[authorIndex])
+			remaining = append(remaining[:authorIndex], remaining[authorIndex+1:]...)
+			fmt.Printf("\n✅ Added: %s\n\n", selected[len(selected)-1])
+			if len(remaining) == 0 {
+				return selected, nil
+			}
+		}
+	}
+}